@@ -0,0 +1,324 @@
+// Package adf converts Atlassian Document Format, the JSON rich-text format
+// Jira Cloud uses for descriptions and comments, to the markdown dialect of
+// Zoho Cliq messages.
+package adf
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node is one node of an ADF document.
+type Node struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Marks   []Mark                 `json:"marks,omitempty"`
+	Content []Node                 `json:"content,omitempty"`
+}
+
+// Mark is formatting applied to a text node.
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// Options customises the conversion.
+type Options struct {
+	// Mention renders a mention of the Jira user with the given account ID
+	// and display text. When nil, mentions are rendered as "@Name".
+	Mention func(accountID string, text string) string
+}
+
+// Parse turns a decoded JSON value, such as the description field of an
+// issue, into a document. It reports false when v is not an ADF document.
+func Parse(v interface{}) (Node, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || m["type"] != "doc" {
+		return Node{}, false
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return Node{}, false
+	}
+	var doc Node
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Node{}, false
+	}
+	return doc, true
+}
+
+// ToMarkdown converts doc to Cliq markdown. Nodes it does not know are
+// rendered as their plain text.
+func ToMarkdown(doc Node, opts Options) string {
+	r := renderer{opts: opts}
+	return strings.TrimSpace(r.block(doc, ""))
+}
+
+// panelIcons prefix the content of info, note, warning, success and error
+// panels.
+var panelIcons = map[string]string{
+	"info":    "ℹ️",
+	"note":    "📝",
+	"warning": "⚠️",
+	"success": "✅",
+	"error":   "❌",
+}
+
+type renderer struct {
+	opts Options
+}
+
+// blocks renders block nodes one per line.
+func (r renderer) blocks(nodes []Node, indent string) string {
+	var lines []string
+	for _, n := range nodes {
+		if s := r.block(n, indent); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// block renders a block node. indent is the prefix for nested list items.
+func (r renderer) block(n Node, indent string) string {
+	switch n.Type {
+	case "doc":
+		return r.blocks(n.Content, indent)
+	case "paragraph":
+		return r.inline(n.Content)
+	case "heading":
+		text := strings.TrimSpace(r.inline(n.Content))
+		if text == "" {
+			return ""
+		}
+		return "*" + text + "*"
+	case "bulletList":
+		return r.list(n, indent, func(int) string { return "- " })
+	case "orderedList":
+		start := intAttr(n.Attrs, "order", 1)
+		return r.list(n, indent, func(i int) string { return strconv.Itoa(start+i) + ". " })
+	case "taskList":
+		return r.list(n, indent, func(int) string { return "" })
+	case "decisionList":
+		return r.list(n, indent, func(int) string { return "➤ " })
+	case "taskItem":
+		box := "☐ "
+		if stringAttr(n.Attrs, "state") == "DONE" {
+			box = "☑ "
+		}
+		return box + r.inline(n.Content)
+	case "decisionItem":
+		return r.inline(n.Content)
+	case "codeBlock":
+		return "```\n" + plainText(n) + "\n```"
+	case "blockquote":
+		return prefixLines(r.blocks(n.Content, ""), "> ")
+	case "rule":
+		return "---"
+	case "panel":
+		content := r.blocks(n.Content, indent)
+		if icon, ok := panelIcons[stringAttr(n.Attrs, "panelType")]; ok {
+			return icon + " " + content
+		}
+		return content
+	case "table":
+		return r.table(n)
+	case "expand", "nestedExpand":
+		content := r.blocks(n.Content, indent)
+		if title := stringAttr(n.Attrs, "title"); title != "" {
+			return "*" + title + "*\n" + content
+		}
+		return content
+	case "mediaSingle", "mediaGroup", "media":
+		return "[attachment]"
+	case "blockCard", "embedCard":
+		return stringAttr(n.Attrs, "url")
+	default:
+		// Inline content at block level, or an unknown node
+		if len(n.Content) > 0 {
+			return r.blocks(n.Content, indent)
+		}
+		return r.inline([]Node{n})
+	}
+}
+
+// list renders the items of a list, prefixing each with marker(i). Nested
+// lists are indented under their item.
+func (r renderer) list(n Node, indent string, marker func(int) string) string {
+	var lines []string
+	for i, item := range n.Content {
+		if item.Type != "listItem" {
+			lines = append(lines, indent+marker(i)+r.block(item, indent))
+			continue
+		}
+		var first string
+		var rest []string
+		for j, child := range item.Content {
+			switch {
+			case j == 0 && child.Type == "paragraph":
+				first = r.inline(child.Content)
+			case child.Type == "bulletList" || child.Type == "orderedList" || child.Type == "taskList":
+				rest = append(rest, r.block(child, indent+"  "))
+			default:
+				rest = append(rest, indent+"  "+r.block(child, indent+"  "))
+			}
+		}
+		lines = append(lines, indent+marker(i)+first)
+		lines = append(lines, rest...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// table renders one line per row with the cells separated by " | ". Header
+// cells are bold.
+func (r renderer) table(n Node) string {
+	var rows []string
+	for _, row := range n.Content {
+		var cells []string
+		for _, cell := range row.Content {
+			text := strings.ReplaceAll(r.blocks(cell.Content, ""), "\n", " ")
+			if cell.Type == "tableHeader" && strings.TrimSpace(text) != "" {
+				text = "*" + strings.TrimSpace(text) + "*"
+			}
+			cells = append(cells, text)
+		}
+		rows = append(rows, strings.Join(cells, " | "))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// inline renders inline nodes.
+func (r renderer) inline(nodes []Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.Type {
+		case "text":
+			b.WriteString(applyMarks(n.Text, n.Marks))
+		case "hardBreak":
+			b.WriteString("\n")
+		case "mention":
+			b.WriteString(r.mention(n))
+		case "emoji":
+			if text := stringAttr(n.Attrs, "text"); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(stringAttr(n.Attrs, "shortName"))
+			}
+		case "inlineCard":
+			b.WriteString(stringAttr(n.Attrs, "url"))
+		case "status":
+			b.WriteString("[" + stringAttr(n.Attrs, "text") + "]")
+		case "date":
+			b.WriteString(formatDate(stringAttr(n.Attrs, "timestamp")))
+		default:
+			if len(n.Content) > 0 {
+				b.WriteString(r.inline(n.Content))
+			} else {
+				b.WriteString(n.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+func (r renderer) mention(n Node) string {
+	id := stringAttr(n.Attrs, "id")
+	text := strings.TrimPrefix(stringAttr(n.Attrs, "text"), "@")
+	if r.opts.Mention != nil {
+		return r.opts.Mention(id, text)
+	}
+	if text == "" {
+		return "@" + id
+	}
+	return "@" + text
+}
+
+// applyMarks wraps text in the Cliq markup for its marks. Whitespace at
+// either end stays outside the markup, where Cliq expects it.
+func applyMarks(text string, marks []Mark) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || len(marks) == 0 {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+
+	code := false
+	for _, m := range marks {
+		code = code || m.Type == "code"
+	}
+
+	// Nothing but a link applies inside code
+	s := trimmed
+	if code {
+		s = "`" + s + "`"
+	}
+	var href string
+	for _, m := range marks {
+		switch {
+		case m.Type == "link":
+			href = stringAttr(m.Attrs, "href")
+		case code:
+		case m.Type == "strong":
+			s = "*" + s + "*"
+		case m.Type == "em":
+			s = "_" + s + "_"
+		case m.Type == "strike":
+			s = "~" + s + "~"
+		}
+	}
+	if href != "" {
+		s = "[" + s + "](" + href + ")"
+	}
+	return lead + s + trail
+}
+
+// plainText returns the text of n and its descendants without formatting.
+func plainText(n Node) string {
+	if n.Type == "hardBreak" {
+		return "\n"
+	}
+	var b strings.Builder
+	b.WriteString(n.Text)
+	for _, c := range n.Content {
+		b.WriteString(plainText(c))
+	}
+	return b.String()
+}
+
+func prefixLines(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDate renders the millisecond timestamp of a date node as a day.
+func formatDate(ms string) string {
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return ms
+	}
+	return time.UnixMilli(n).UTC().Format("2006-01-02")
+}
+
+func stringAttr(attrs map[string]interface{}, key string) string {
+	switch v := attrs[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func intAttr(attrs map[string]interface{}, key string, fallback int) int {
+	if v, ok := attrs[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
@@ -0,0 +1,82 @@
+package adf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, file string) Node {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+	doc, ok := Parse(v)
+	if !ok {
+		t.Fatalf("%s is not an ADF document", file)
+	}
+	return doc
+}
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no *.json files in testdata")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			doc := load(t, file)
+			want, err := os.ReadFile(strings.TrimSuffix(file, ".json") + ".md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ToMarkdown(doc, Options{})
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("ToMarkdown(%s) =\n%s\nwant\n%s", file, got, want)
+			}
+		})
+	}
+}
+
+func TestMentionOption(t *testing.T) {
+	doc := load(t, filepath.Join("testdata", "mentions.json"))
+	got := ToMarkdown(doc, Options{Mention: func(accountID string, text string) string {
+		return "{@" + accountID + "|" + text + "}"
+	}})
+	want := "{@5b10ac8d82e05b22cc7d4ef5|Bob Smith} and {@712020:f3b2|Ann Lee} please look, {@557058:c0de|} too.\n" +
+		"- Owner: {@5b10ac8d82e05b22cc7d4ef5|Bob Smith}"
+	if got != want {
+		t.Errorf("ToMarkdown with Mention =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, v := range []interface{}{
+		nil,
+		"plain wiki text",
+		map[string]interface{}{"type": "paragraph"},
+		map[string]interface{}{"content": []interface{}{}},
+	} {
+		if _, ok := Parse(v); ok {
+			t.Errorf("Parse(%v) = true, want false", v)
+		}
+	}
+	doc, ok := Parse(map[string]interface{}{"type": "doc", "content": []interface{}{}})
+	if !ok {
+		t.Fatal("Parse of an empty document = false")
+	}
+	if got := ToMarkdown(doc, Options{}); got != "" {
+		t.Errorf("empty document renders %q", got)
+	}
+}
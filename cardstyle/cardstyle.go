@@ -0,0 +1,162 @@
+// Package cardstyle picks how a message card looks from the priority, type
+// and status of its issue, so that a blocker stands out from a sub-task.
+package cardstyle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"zogoapps/cliq"
+)
+
+// Rule styles the cards of one priority, issue type or status category.
+// Empty fields leave the choice to other rules.
+type Rule struct {
+	Theme     string `json:"theme,omitempty"`
+	Emoji     string `json:"emoji,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// Styles holds the rules, by priority name, issue type name and status
+// category color name. Names are matched without regard to case.
+type Styles struct {
+	Priorities map[string]Rule `json:"priorities"`
+	Types      map[string]Rule `json:"types"`
+	Statuses   map[string]Rule `json:"statusColors"`
+	// Icons says whether the issue type's icon is used as the thumbnail
+	// when no rule sets one and the icon can be fetched.
+	Icons *IconChecker `json:"-"`
+}
+
+// Style is the look chosen for one message.
+type Style struct {
+	// Theme is the card theme, one of the cliq.Theme constants.
+	Theme string
+	// Emoji marks the priority and type, for example "🔴 🐞".
+	Emoji string
+	// Thumbnail is the image shown on the card.
+	Thumbnail string
+	// StatusEmoji is a dot of the status category's color. Cliq cards have
+	// no color of their own.
+	StatusEmoji string
+}
+
+// Defaults are the rules used when no file overrides them.
+var Defaults = Styles{
+	Priorities: map[string]Rule{
+		"highest":  {Theme: cliq.ThemeModernInline, Emoji: "🔴"},
+		"blocker":  {Theme: cliq.ThemeModernInline, Emoji: "⛔"},
+		"critical": {Theme: cliq.ThemeModernInline, Emoji: "🔴"},
+		"high":     {Emoji: "🟠"},
+		"major":    {Emoji: "🟠"},
+		"medium":   {Emoji: "🟡"},
+		"low":      {Emoji: "🟢"},
+		"minor":    {Emoji: "🟢"},
+		"lowest":   {Emoji: "🔵"},
+		"trivial":  {Emoji: "⚪"},
+	},
+	Types: map[string]Rule{
+		"bug":         {Emoji: "🐞"},
+		"story":       {Emoji: "📗"},
+		"task":        {Emoji: "☑️"},
+		"sub-task":    {Emoji: "🔹"},
+		"subtask":     {Emoji: "🔹"},
+		"epic":        {Emoji: "⚡"},
+		"improvement": {Emoji: "⬆️"},
+		"new feature": {Emoji: "✨"},
+		"incident":    {Theme: cliq.ThemeModernInline, Emoji: "🚨"},
+	},
+	Statuses: map[string]Rule{
+		"blue-gray":   {Emoji: "🔵"},
+		"yellow":      {Emoji: "🟡"},
+		"green":       {Emoji: "🟢"},
+		"medium-gray": {Emoji: "⚪"},
+	},
+}
+
+// Load returns Defaults with the rules in the JSON file at path laid over
+// them. A rule in the file replaces the default rule of the same name. An
+// empty path returns Defaults.
+func Load(path string) (Styles, error) {
+	styles := Styles{
+		Priorities: copyRules(Defaults.Priorities),
+		Types:      copyRules(Defaults.Types),
+		Statuses:   copyRules(Defaults.Statuses),
+	}
+	if path == "" {
+		return styles, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return styles, fmt.Errorf("card styles: %w", err)
+	}
+	var file Styles
+	if err := json.Unmarshal(data, &file); err != nil {
+		return styles, fmt.Errorf("card styles %s: %w", path, err)
+	}
+	for _, pair := range []struct{ dst, src map[string]Rule }{
+		{styles.Priorities, file.Priorities},
+		{styles.Types, file.Types},
+		{styles.Statuses, file.Statuses},
+	} {
+		for name, rule := range pair.src {
+			pair.dst[strings.ToLower(name)] = rule
+		}
+	}
+	return styles, nil
+}
+
+// Resolve picks the style of a card for the issue fields of a webhook
+// body, as decoded into maps. The priority rule wins over the type rule.
+func (s Styles) Resolve(fields map[string]interface{}) Style {
+	priority := s.Priorities[strings.ToLower(nested(fields, "priority", "name"))]
+	issueType := s.Types[strings.ToLower(nested(fields, "issuetype", "name"))]
+	status := s.Statuses[strings.ToLower(nested(fields, "status", "statusCategory", "colorName"))]
+
+	style := Style{
+		Theme:       first(priority.Theme, issueType.Theme, cliq.ThemePrompt),
+		Emoji:       strings.TrimSpace(priority.Emoji + " " + issueType.Emoji),
+		Thumbnail:   first(issueType.Thumbnail, priority.Thumbnail),
+		StatusEmoji: status.Emoji,
+	}
+	if style.Thumbnail == "" && s.Icons != nil {
+		if icon := nested(fields, "issuetype", "iconUrl"); s.Icons.Reachable(icon) {
+			style.Thumbnail = icon
+		}
+	}
+	if style.Thumbnail == "" {
+		style.Thumbnail = cliq.DefaultThumbnail
+	}
+	return style
+}
+
+// nested reads a string at the path of keys in nested JSON objects.
+func nested(m map[string]interface{}, keys ...string) string {
+	for i, key := range keys {
+		if i == len(keys)-1 {
+			s, _ := m[key].(string)
+			return s
+		}
+		m, _ = m[key].(map[string]interface{})
+	}
+	return ""
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func copyRules(rules map[string]Rule) map[string]Rule {
+	c := make(map[string]Rule, len(rules))
+	for k, v := range rules {
+		c[k] = v
+	}
+	return c
+}
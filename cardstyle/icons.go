@@ -0,0 +1,126 @@
+package cardstyle
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// iconTimeout bounds the requests that check an icon, since a card waits
+// for them. iconRetryAfter is how long a failed check is remembered before
+// the icon is checked again, so that a Jira or network hiccup does not cost
+// the thumbnail for the life of the instance.
+const (
+	iconTimeout    = time.Second
+	iconRetryAfter = 5 * time.Minute
+)
+
+// now is the current time. Tests replace it.
+var now = time.Now
+
+// IconChecker tells whether an icon URL can be used as a thumbnail. Cliq
+// fetches thumbnails itself and cannot log in to Jira or show SVG, so an
+// icon is only used if it can be fetched anonymously as a raster image.
+// An icon that passes is not checked again; one that fails is checked again
+// after a few minutes.
+type IconChecker struct {
+	// HTTPClient is used for the checks, http.DefaultClient when nil. Each
+	// check is given a short timeout either way.
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]iconCheck
+	// checking holds a channel for each icon being checked, closed when
+	// the check ends.
+	checking map[string]chan struct{}
+}
+
+// iconCheck is the outcome of checking an icon. A failure is kept until
+// expires; a success has no expiry.
+type iconCheck struct {
+	ok      bool
+	expires time.Time
+}
+
+// NewIconChecker returns an IconChecker with an empty cache.
+func NewIconChecker() *IconChecker {
+	return &IconChecker{}
+}
+
+// Reachable reports whether icon can be fetched as an image. The check
+// runs without holding the lock, so checking one icon does not hold up
+// lookups of others. Concurrent callers for the same icon wait for its
+// check.
+func (c *IconChecker) Reachable(icon string) bool {
+	if icon == "" {
+		return false
+	}
+	c.mu.Lock()
+	for {
+		if result, seen := c.cache[icon]; seen && (result.ok || now().Before(result.expires)) {
+			c.mu.Unlock()
+			return result.ok
+		}
+		done, busy := c.checking[icon]
+		if !busy {
+			break
+		}
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+	}
+	if c.cache == nil {
+		c.cache = map[string]iconCheck{}
+		c.checking = map[string]chan struct{}{}
+	}
+	done := make(chan struct{})
+	c.checking[icon] = done
+	c.mu.Unlock()
+
+	ok := c.check(icon)
+
+	c.mu.Lock()
+	c.cache[icon] = iconCheck{ok: ok, expires: now().Add(iconRetryAfter)}
+	delete(c.checking, icon)
+	close(done)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *IconChecker) check(icon string) bool {
+	u, err := url.Parse(icon)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	// One deadline covers the GET that follows a refused HEAD too
+	ctx, cancel := context.WithTimeout(context.Background(), iconTimeout)
+	defer cancel()
+	resp, err := request(ctx, client, http.MethodHead, icon)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = request(ctx, client, http.MethodGet, icon)
+	}
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	contentType := resp.Header.Get("Content-Type")
+	return resp.StatusCode/100 == 2 &&
+		strings.HasPrefix(contentType, "image/") &&
+		!strings.HasPrefix(contentType, "image/svg")
+}
+
+func request(ctx context.Context, client *http.Client, method string, icon string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, icon, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,122 @@
+package cardstyle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func iconServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReachable(t *testing.T) {
+	srv := iconServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bug.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/bug.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+		case "/head-refused.png":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+		case "/login":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	tests := []struct {
+		icon string
+		want bool
+	}{
+		{srv.URL + "/bug.png", true},
+		{srv.URL + "/head-refused.png", true},
+		{srv.URL + "/bug.svg", false},
+		{srv.URL + "/login", false},
+		{srv.URL + "/missing.png", false},
+		{"ftp://example.com/bug.png", false},
+		{"", false},
+	}
+	c := NewIconChecker()
+	for _, tt := range tests {
+		if got := c.Reachable(tt.icon); got != tt.want {
+			t.Errorf("Reachable(%q) = %v, want %v", tt.icon, got, tt.want)
+		}
+	}
+}
+
+func TestReachableRetriesFailures(t *testing.T) {
+	var requests, up int32
+	srv := iconServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+	})
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	saved := now
+	now = func() time.Time { return clock }
+	defer func() { now = saved }()
+
+	c := NewIconChecker()
+	icon := srv.URL + "/bug.png"
+	if c.Reachable(icon) {
+		t.Fatal("Reachable while the server is down")
+	}
+	atomic.StoreInt32(&up, 1)
+
+	// The failure is remembered for a while
+	clock = clock.Add(iconRetryAfter - time.Second)
+	if c.Reachable(icon) {
+		t.Error("failure was not remembered")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d requests, want 1 while the failure is remembered", n)
+	}
+
+	// and then the icon is checked again
+	clock = clock.Add(2 * time.Second)
+	if !c.Reachable(icon) {
+		t.Error("icon not checked again after the failure expired")
+	}
+
+	// A success is kept
+	clock = clock.Add(24 * time.Hour)
+	if !c.Reachable(icon) {
+		t.Error("success was not kept")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestReachableTimeout(t *testing.T) {
+	srv := iconServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	start := time.Now()
+	if NewIconChecker().Reachable(srv.URL + "/slow.png") {
+		t.Error("Reachable for an icon that never loads")
+	}
+	if elapsed := time.Since(start); elapsed > iconTimeout+500*time.Millisecond {
+		t.Errorf("check took %s, want at most about %s", elapsed, iconTimeout)
+	}
+}
@@ -0,0 +1,153 @@
+// Package changelog turns the changelog of an issue updated webhook into
+// rows that can be shown in a message, one per changed field.
+package changelog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxLength is the length above which values are cut down to the part
+// that changed.
+const DefaultMaxLength = 80
+
+// snippetContext is how many characters of unchanged text are kept on each
+// side of the change in a diff snippet.
+const snippetContext = 20
+
+// longTextFields always change as a whole block of text, so they are shown
+// as diff snippets whatever their length.
+var longTextFields = map[string]bool{
+	"description": true,
+	"environment": true,
+}
+
+// Item is one entry of the webhook's changelog.
+type Item struct {
+	Field      string
+	FieldType  string
+	FieldID    string
+	From       string
+	FromString string
+	To         string
+	ToString   string
+}
+
+// Change is one changed field, ready to display.
+type Change struct {
+	// Field is the name of the field, for example "Status" or "Story Points".
+	Field string
+	// From and To are the old and new values. Either is empty when the field
+	// had or has no value.
+	From string
+	To   string
+}
+
+// Options controls how values are displayed.
+type Options struct {
+	// Assignee is the display name of the issue's current assignee. It is
+	// used for the new value of an assignee change when the changelog only
+	// carries the account ID.
+	Assignee string
+	// MaxLength is the length above which values are cut down to the part
+	// that changed. DefaultMaxLength is used when it is zero.
+	MaxLength int
+}
+
+// Changes returns a row for each changelog item.
+func Changes(items []Item, opts Options) []Change {
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+
+	changes := make([]Change, 0, len(items))
+	for _, item := range items {
+		from, to := displayValue(item.FromString, item.From), displayValue(item.ToString, item.To)
+		if item.Field == "assignee" {
+			// Jira Cloud puts account IDs in from/to and the names in the strings
+			from = item.FromString
+			to = item.ToString
+			if to == "" && item.To != "" {
+				to = opts.Assignee
+			}
+		}
+
+		if longTextFields[strings.ToLower(item.Field)] || utf8.RuneCountInString(from) > maxLength || utf8.RuneCountInString(to) > maxLength {
+			from, to = Snippet(from, to, maxLength)
+		}
+		changes = append(changes, Change{Field: fieldName(item.Field), From: from, To: to})
+	}
+	return changes
+}
+
+// displayValue prefers the human-readable string Jira sends and falls back
+// to the raw value.
+func displayValue(str string, raw string) string {
+	if str != "" {
+		return str
+	}
+	return raw
+}
+
+// fieldName capitalises built-in field names such as "status". Custom
+// fields already arrive with their display name.
+func fieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	if r == utf8.RuneError {
+		return name
+	}
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// Snippet cuts from and to down to the part that differs, keeping a little
+// unchanged text around it and marking the cuts with an ellipsis. Each
+// result is at most maxLength runes long, plus the ellipses.
+func Snippet(from string, to string, maxLength int) (string, string) {
+	a, b := []rune(collapseSpace(from)), []rune(collapseSpace(to))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	start := prefix - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	return cut(a, start, len(a)-suffix+snippetContext, maxLength), cut(b, start, len(b)-suffix+snippetContext, maxLength)
+}
+
+// cut returns text[start:end], limited to maxLength runes, with ellipses
+// where text was left out.
+func cut(text []rune, start int, end int, maxLength int) string {
+	if end > len(text) {
+		end = len(text)
+	}
+	if start >= end {
+		return ""
+	}
+	truncated := false
+	if maxLength > 0 && end-start > maxLength {
+		end = start + maxLength
+		truncated = true
+	}
+	s := strings.TrimSpace(string(text[start:end]))
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) || truncated {
+		s += "…"
+	}
+	return s
+}
+
+// collapseSpace puts multi-line text on one line.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,126 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		opts Options
+		want Change
+	}{
+		{
+			name: "strings over ids",
+			item: Item{Field: "status", FieldID: "status", From: "10000", FromString: "To Do", To: "3", ToString: "In Progress"},
+			want: Change{Field: "Status", From: "To Do", To: "In Progress"},
+		},
+		{
+			name: "ids without strings",
+			item: Item{Field: "Sprint", FieldID: "customfield_10020", From: "", To: "42"},
+			want: Change{Field: "Sprint", From: "", To: "42"},
+		},
+		{
+			name: "string without id",
+			item: Item{Field: "summary", FieldID: "summary", FromString: "Old title", ToString: "New title"},
+			want: Change{Field: "Summary", From: "Old title", To: "New title"},
+		},
+		{
+			name: "value set",
+			item: Item{Field: "resolution", FieldID: "resolution", To: "10000", ToString: "Done"},
+			want: Change{Field: "Resolution", From: "", To: "Done"},
+		},
+		{
+			name: "value cleared",
+			item: Item{Field: "Story Points", FieldID: "customfield_10016", FromString: "5"},
+			want: Change{Field: "Story Points", From: "5", To: ""},
+		},
+		{
+			name: "assignee with names",
+			item: Item{Field: "assignee", From: "acc-ann", FromString: "Ann Lee", To: "acc-bob", ToString: "Bob Smith"},
+			want: Change{Field: "Assignee", From: "Ann Lee", To: "Bob Smith"},
+		},
+		{
+			name: "assignee with only the new account ID",
+			item: Item{Field: "assignee", From: "acc-ann", To: "acc-bob"},
+			opts: Options{Assignee: "Bob Smith"},
+			want: Change{Field: "Assignee", From: "", To: "Bob Smith"},
+		},
+		{
+			name: "assignee removed",
+			item: Item{Field: "assignee", From: "acc-ann", FromString: "Ann Lee"},
+			opts: Options{Assignee: "Bob Smith"},
+			want: Change{Field: "Assignee", From: "Ann Lee", To: ""},
+		},
+		{
+			name: "Server assignee by username",
+			item: Item{Field: "assignee", From: "ann", FromString: "Ann Lee", To: "bob", ToString: "Bob Smith"},
+			want: Change{Field: "Assignee", From: "Ann Lee", To: "Bob Smith"},
+		},
+		{
+			name: "short description",
+			item: Item{Field: "description", FromString: "Fails on Safari", ToString: "Fails on Safari and Firefox"},
+			want: Change{Field: "Description", From: "Fails on Safari", To: "Fails on Safari and Firefox"},
+		},
+		{
+			name: "long value",
+			item: Item{Field: "summary", FromString: strings.Repeat("a", 50) + " old " + strings.Repeat("z", 50), ToString: strings.Repeat("a", 50) + " new " + strings.Repeat("z", 50)},
+			// 20 characters of context on each side, the spaces included
+			want: Change{Field: "Summary", From: "…" + strings.Repeat("a", 19) + " old " + strings.Repeat("z", 19) + "…", To: "…" + strings.Repeat("a", 19) + " new " + strings.Repeat("z", 19) + "…"},
+		},
+		{
+			name: "shorter MaxLength",
+			item: Item{Field: "labels", FromString: "checkout ui", ToString: "checkout ui web"},
+			opts: Options{MaxLength: 12},
+			want: Change{Field: "Labels", From: "checkout ui", To: "checkout ui…"},
+		},
+	}
+	for _, tt := range tests {
+		got := Changes([]Item{tt.item}, tt.opts)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: Changes = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChangesKeepsOrder(t *testing.T) {
+	got := Changes([]Item{
+		{Field: "status", ToString: "Done"},
+		{Field: "resolution", ToString: "Fixed"},
+		{Field: "assignee", FromString: "Ann"},
+	}, Options{})
+	var fields []string
+	for _, c := range got {
+		fields = append(fields, c.Field)
+	}
+	if strings.Join(fields, ",") != "Status,Resolution,Assignee" {
+		t.Errorf("fields = %v", fields)
+	}
+	if changes := Changes(nil, Options{}); changes == nil || len(changes) != 0 {
+		t.Errorf("Changes(nil) = %#v, want an empty list", changes)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		from, to         string
+		max              int
+		wantFrom, wantTo string
+	}{
+		{"same", "same", 80, "same", "same"},
+		{"", "new text", 80, "", "new text"},
+		{"old text", "", 80, "old text", ""},
+		{"The checkout page fails on Safari when the card is declined.", "The checkout page fails on Firefox when the card is declined.",
+			80, "…ckout page fails on Safari when the card is de…", "…ckout page fails on Firefox when the card is de…"},
+		{"line one\n\nline   two", "line one\nline two\nline three", 80, "line one line two", "line one line two line three"},
+		{"abc", "abcdefghijklmnop", 5, "abc", "abcde…"},
+	}
+	for _, tt := range tests {
+		from, to := Snippet(tt.from, tt.to, tt.max)
+		if from != tt.wantFrom || to != tt.wantTo {
+			t.Errorf("Snippet(%q, %q, %d) = %q, %q, want %q, %q", tt.from, tt.to, tt.max, from, to, tt.wantFrom, tt.wantTo)
+		}
+	}
+}
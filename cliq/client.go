@@ -0,0 +1,149 @@
+// Package cliq posts messages to Zoho Cliq channels.
+package cliq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client sends messages to one Cliq channel endpoint.
+type Client struct {
+	// Endpoint is the channel's message API URL, for example
+	// https://cliq.zoho.com/api/v2/channelsbyname/jira/message.
+	Endpoint string
+	// Token is the Zoho Cliq API token sent as the zapikey parameter.
+	Token string
+	// HTTPClient is used for requests. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+	// Retry controls how temporary failures are retried.
+	Retry RetryPolicy
+}
+
+// NewClient returns a Client for the given channel endpoint and API token.
+func NewClient(endpoint string, token string) *Client {
+	return &Client{Endpoint: endpoint, Token: token, Retry: DefaultRetryPolicy}
+}
+
+// Response is the reply Cliq sent for a message.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Error is returned by Send when Cliq answers with a non-2xx status.
+type Error struct {
+	StatusCode int
+	Status     string
+	Body       string
+	// RetryAfter is the delay Cliq asked for in its Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("cliq: %s", e.Status)
+	}
+	return fmt.Sprintf("cliq: %s: %s", e.Status, e.Body)
+}
+
+// Send posts msg to the channel. Replies with status 429 or 5xx and network
+// errors are retried according to c.Retry, as long as the next attempt can
+// start before ctx's deadline. A Retry-After longer than c.Retry.MaxDelay or
+// than the time left is waited for in full or not at all, so Send then
+// returns the retryable error at once. A non-2xx reply is returned as both the
+// Response and an *Error; use IsRetryable to tell whether it is worth
+// sending the message again later.
+func (c *Client) Send(ctx context.Context, msg Message) (*Response, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("cliq: encoding message: %w", err)
+	}
+
+	endpoint, err := c.url()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.post(ctx, endpoint, payload)
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return resp, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		delay, ok := c.Retry.backoff(attempt, retryAfter)
+		if !ok {
+			return resp, fmt.Errorf("%w (not retried: Retry-After %s is over %s)", err, delay, c.Retry.MaxDelay)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, fmt.Errorf("%w (not retried: deadline reached)", err)
+		}
+
+		log.Printf("Cliq delivery attempt %d of %d failed, retrying in %s: %v", attempt, attempts, delay, err)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return resp, fmt.Errorf("%w (not retried: %v)", err, sleepErr)
+		}
+	}
+}
+
+// post makes a single delivery attempt.
+func (c *Client) post(ctx context.Context, endpoint string, payload []byte) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("cliq: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cliq: sending message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cliq: reading response: %w", err)
+	}
+	response := &Response{StatusCode: resp.StatusCode, Body: body}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return response, &Error{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return response, nil
+}
+
+// url returns the endpoint with the API token added to its query.
+func (c *Client) url() (string, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("cliq: invalid endpoint: %w", err)
+	}
+	query := u.Query()
+	query.Set("zapikey", c.Token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
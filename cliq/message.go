@@ -0,0 +1,91 @@
+package cliq
+
+// Card themes supported by Zoho Cliq.
+const (
+	ThemeModernInline = "modern-inline"
+	ThemePoll         = "poll"
+	ThemePrompt       = "prompt"
+)
+
+// Button types. Cliq draws "+" buttons as positive (green) and "-" buttons
+// as negative (red).
+const (
+	ButtonPositive = "+"
+	ButtonNegative = "-"
+)
+
+// Slide types. A label slide's data is a list of single-entry maps, one per
+// label and value; a table slide's data is a Table.
+const (
+	SlideLabel = "label"
+	SlideTable = "table"
+	SlideText  = "text"
+)
+
+// ActionOpenURL opens the URL in Action.Data.Web when the button is clicked.
+const ActionOpenURL = "open.url"
+
+// Message is the body posted to a Cliq channel.
+type Message struct {
+	Text    string   `json:"text"`
+	Card    *Card    `json:"card,omitempty"`
+	Buttons []Button `json:"buttons,omitempty"`
+	Slides  []Slide  `json:"slides,omitempty"`
+}
+
+// Card controls how the message is framed in the channel.
+type Card struct {
+	Title     string `json:"title,omitempty"`
+	Theme     string `json:"theme,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	Icon      string `json:"icon,omitempty"`
+}
+
+// Button is shown underneath the message.
+type Button struct {
+	Label  string `json:"label"`
+	Type   string `json:"type,omitempty"`
+	Action Action `json:"action"`
+}
+
+// Action is what happens when a Button is clicked.
+type Action struct {
+	Type string     `json:"type"`
+	Data ActionData `json:"data"`
+}
+
+// ActionData carries the parameters of an Action.
+type ActionData struct {
+	Web string `json:"web,omitempty"`
+}
+
+// Slide is an extra block of content attached to the message, such as a
+// table, a list of labels or plain text.
+type Slide struct {
+	Type    string      `json:"type"`
+	Title   string      `json:"title,omitempty"`
+	Data    interface{} `json:"data"`
+	Buttons []Button    `json:"buttons,omitempty"`
+}
+
+// Table is the data of a table slide. Each row maps a header to its cell.
+type Table struct {
+	Headers []string            `json:"headers"`
+	Rows    []map[string]string `json:"rows"`
+}
+
+// OpenURLButton returns a positive button that opens link in the browser.
+func OpenURLButton(label string, link string) Button {
+	return Button{
+		Label: label,
+		Type:  ButtonPositive,
+		Action: Action{
+			Type: ActionOpenURL,
+			Data: ActionData{Web: link},
+		},
+	}
+}
+
+// DefaultThumbnail is the announcement icon used on cards that do not set
+// their own thumbnail.
+const DefaultThumbnail = "https://www.zoho.com/cliq/help/restapi/images/announce_icon.png"
@@ -0,0 +1,103 @@
+package cliq
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how Send retries messages that Cliq could not accept
+// right now.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Zero or one disables retries.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the first backoff. It doubles with each
+	// attempt up to MaxDelay, and the actual delay is picked at random below
+	// that bound.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not
+	// shortened; Send gives up instead.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created with NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// IsRetryable reports whether err, returned by Send, is a temporary failure
+// that may succeed if the message is sent again later: a 429 or 5xx reply, or
+// a network error.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	// Transport failures come back from http.Client as *url.Error
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// backoff returns how long to wait before the given retry (1 for the first
+// retry). A Retry-After from the server takes precedence over the jittered
+// exponential delay; if it is longer than MaxDelay, backoff returns false
+// and the message should not be retried now.
+func (p RetryPolicy) backoff(retry int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, p.MaxDelay <= 0 || retryAfter <= p.MaxDelay
+	}
+	if p.BaseDelay <= 0 {
+		return 0, true
+	}
+	bound := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || bound < p.MaxDelay); i++ {
+		bound *= 2
+	}
+	if p.MaxDelay > 0 && bound > p.MaxDelay {
+		bound = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(bound) + 1)), true
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+package cliq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"429", &Error{StatusCode: 429}, true},
+		{"500", &Error{StatusCode: 500}, true},
+		{"503", &Error{StatusCode: 503}, true},
+		{"400", &Error{StatusCode: 400}, false},
+		{"401", &Error{StatusCode: 401}, false},
+		{"404", &Error{StatusCode: 404}, false},
+		{"wrapped 503", fmt.Errorf("%w (not retried: deadline reached)", &Error{StatusCode: 503}), true},
+		{"network error", &url.Error{Op: "Post", URL: "http://cliq", Err: errors.New("connection refused")}, true},
+		{"deadline", &url.Error{Op: "Post", URL: "http://cliq", Err: context.DeadlineExceeded}, true},
+		{"canceled", &url.Error{Op: "Post", URL: "http://cliq", Err: context.Canceled}, false},
+		{"other error", errors.New("cliq: encoding message"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	bounds := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}
+	for i, bound := range bounds {
+		retry := i + 1
+		seen := map[time.Duration]bool{}
+		for n := 0; n < 200; n++ {
+			d, ok := p.backoff(retry, 0)
+			if !ok || d < 0 || d > bound {
+				t.Fatalf("backoff(%d) = %s, want between 0 and %s", retry, d, bound)
+			}
+			seen[d] = true
+		}
+		// The delay is jittered, not fixed
+		if len(seen) < 10 {
+			t.Errorf("backoff(%d) gave only %d distinct delays in 200 tries", retry, len(seen))
+		}
+	}
+
+	if d, ok := p.backoff(1, 500*time.Millisecond); !ok || d != 500*time.Millisecond {
+		t.Errorf("backoff with Retry-After 500ms = %s, %v", d, ok)
+	}
+	if d, ok := p.backoff(1, time.Second); !ok || d != time.Second {
+		t.Errorf("backoff with Retry-After 1s = %s, %v, want MaxDelay 1s", d, ok)
+	}
+	// A longer Retry-After is not shortened to MaxDelay
+	if d, ok := p.backoff(1, time.Minute); ok || d != time.Minute {
+		t.Errorf("backoff with Retry-After 1m = %s, %v, want 1m and no retry", d, ok)
+	}
+	if d, ok := (RetryPolicy{}).backoff(3, time.Minute); !ok || d != time.Minute {
+		t.Errorf("backoff with Retry-After 1m without MaxDelay = %s, %v", d, ok)
+	}
+	if d, ok := (RetryPolicy{}).backoff(3, 0); !ok || d != 0 {
+		t.Errorf("backoff without BaseDelay = %s, %v, want 0", d, ok)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"5", 5 * time.Second},
+		{"120", 2 * time.Minute},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"Sat, 17 Oct 2026 12:01:00 GMT", time.Minute},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// replies returns a server that answers with the given statuses in turn,
+// repeating the last one, and counts the requests in calls.
+func replies(t *testing.T, calls *int32, retryAfter string, statuses ...int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		status := statuses[len(statuses)-1]
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		if retryAfter != "" && status != http.StatusOK {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testClient(endpoint string, p RetryPolicy) *Client {
+	c := NewClient(endpoint, "token")
+	c.Retry = p
+	return c
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := replies(t, &calls, "", 500, 502, 200)
+
+	resp, err := testClient(srv.URL, fastRetry).Send(context.Background(), Message{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("%d requests, want 3", calls)
+	}
+}
+
+func TestSendHonoursRetryAfter(t *testing.T) {
+	var calls int32
+	srv := replies(t, &calls, "1", 429, 200)
+
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+	start := time.Now()
+	if _, err := testClient(srv.URL, p).Send(context.Background(), Message{Text: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("retried after %s, want the 1s of Retry-After", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("%d requests, want 2", calls)
+	}
+}
+
+func TestSendGivesUpOnLongRetryAfter(t *testing.T) {
+	var calls int32
+	srv := replies(t, &calls, "30", 429, 200)
+
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+	start := time.Now()
+	_, err := testClient(srv.URL, p).Send(context.Background(), Message{Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "not retried: Retry-After 30s is over 1s") {
+		t.Fatalf("Send error = %v, want Retry-After over MaxDelay", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("error is not retryable: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Send took %s, want it to give up without waiting", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("%d requests, want 1", calls)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{400, 401, 404} {
+		var calls int32
+		srv := replies(t, &calls, "", status)
+
+		resp, err := testClient(srv.URL, fastRetry).Send(context.Background(), Message{Text: "hi"})
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+			t.Fatalf("%d: Send error = %v, want *Error with that status", status, err)
+		}
+		if IsRetryable(err) {
+			t.Errorf("%d: error is retryable", status)
+		}
+		if resp == nil || resp.StatusCode != status {
+			t.Errorf("%d: response = %+v", status, resp)
+		}
+		if calls != 1 {
+			t.Errorf("%d: %d requests, want 1", status, calls)
+		}
+	}
+}
+
+func TestSendStopsAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	srv := replies(t, &calls, "", 503)
+
+	_, err := testClient(srv.URL, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}).Send(context.Background(), Message{Text: "hi"})
+	if !IsRetryable(err) {
+		t.Fatalf("Send error = %v, want a retryable error", err)
+	}
+	if calls != 3 {
+		t.Errorf("%d requests, want 3", calls)
+	}
+}
+
+func TestSendStopsAtDeadline(t *testing.T) {
+	var calls int32
+	srv := replies(t, &calls, "5", 503)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Second}
+	start := time.Now()
+	_, err := testClient(srv.URL, p).Send(ctx, Message{Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "deadline reached") {
+		t.Fatalf("Send error = %v, want deadline reached", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("error at the deadline is not retryable: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Send took %s, want it to give up without waiting", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("%d requests, want 1", calls)
+	}
+}
@@ -0,0 +1,376 @@
+// Package config loads and validates the settings of the bridge from the
+// environment. It runs once at cold start so that a missing or malformed
+// variable is reported up front instead of in the middle of a request.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"zogoapps/cardstyle"
+	"zogoapps/i18n"
+	"zogoapps/identity"
+	"zogoapps/jql"
+	"zogoapps/routing"
+	"zogoapps/rules"
+	"zogoapps/templates"
+)
+
+// Values accepted by WEBHOOK_SIGNATURE_MODE.
+const (
+	// SignatureOff ignores X-Hub-Signature and relies on the lamda-auth query parameter.
+	SignatureOff = "off"
+	// SignatureOptional accepts either a valid signature or the query parameter.
+	// A signature that is present but wrong is always rejected.
+	SignatureOptional = "optional"
+	// SignatureRequired rejects every request without a valid signature.
+	SignatureRequired = "required"
+)
+
+// Defaults of DELIVERY_CONCURRENCY and DELIVERY_TIMEOUT.
+const (
+	DefaultDeliveryConcurrency = 4
+	DefaultDeliveryTimeout     = 20 * time.Second
+)
+
+// DefaultCommentExcerptLength is how many characters of a comment are shown
+// when COMMENT_EXCERPT_LENGTH is not set.
+const DefaultCommentExcerptLength = 300
+
+// Config holds the validated settings of the bridge.
+type Config struct {
+	// CliqAPIToken is the Zoho Cliq API token (ZOHO_CLIQ_API_TOKEN).
+	CliqAPIToken string
+	// JiraURL is the base URL of the Jira instance without a trailing slash (JIRA_URL).
+	JiraURL string
+	// ChannelEndpoint is the message API URL of the Cliq channel (CHANNEL_ENDPOINT).
+	ChannelEndpoint string
+	// ChannelFilter is the JQL query issues must match for the channel to
+	// get their events (CHANNEL_FILTER), or nil for every issue.
+	ChannelFilter *jql.Query
+	// RoutesFile maps projects, issue types, components and labels to
+	// channels (ROUTES_FILE).
+	RoutesFile string
+	// Routes picks the channels of each event.
+	Routes *routing.Table
+	// DeliveryConcurrency is how many channels an event is delivered to at
+	// a time (DELIVERY_CONCURRENCY).
+	DeliveryConcurrency int
+	// DeliveryTimeout bounds delivery to one channel, retries included, for
+	// channels without a timeout of their own (DELIVERY_TIMEOUT).
+	DeliveryTimeout time.Duration
+	// RulesFile holds the rules that send, drop or reroute events
+	// (RULES_FILE).
+	RulesFile string
+	// Rules decide what happens to each event, or are nil to send every
+	// event along its route.
+	Rules *rules.Set
+	// Locale is the language messages to the channel are written in
+	// (CHANNEL_LOCALE, default "en").
+	Locale string
+	// TimeZone is the IANA time zone times are shown in for the channel
+	// (CHANNEL_TIMEZONE). When empty, the zone of the Jira user who caused
+	// the event is used.
+	TimeZone string
+	// CatalogDir holds message catalogs that add to or change the embedded
+	// ones (CATALOG_DIR).
+	CatalogDir string
+	// Catalogs are the message catalogs.
+	Catalogs *i18n.Catalogs
+	// Credentials are the accepted lamda-auth values (LAMBDA_CRED and LAMBDA_CREDS).
+	Credentials []Credential
+	// SignatureMode is one of SignatureOff, SignatureOptional or SignatureRequired (WEBHOOK_SIGNATURE_MODE).
+	SignatureMode string
+	// WebhookSecret is the secret Jira signs webhook bodies with (WEBHOOK_SECRET).
+	WebhookSecret string
+	// DeadLetter says where undeliverable messages are kept.
+	DeadLetter DeadLetterConfig
+	// Dedup says where processed events are remembered.
+	Dedup DedupConfig
+	// TemplateDir holds files overriding the embedded message templates (TEMPLATE_DIR).
+	TemplateDir string
+	// Templates are the parsed message templates.
+	Templates *templates.Set
+	// CardStyleFile overrides the rules that style each card (CARD_STYLE_FILE).
+	CardStyleFile string
+	// CardIconThumbnails says whether reachable issue type icons are used as
+	// thumbnails (CARD_ICON_THUMBNAILS, on unless set to "off").
+	CardIconThumbnails bool
+	// Slides lists the slides attached by each template, by template name
+	// (SLIDES_<NAME>).
+	Slides map[string][]string
+	// IdentityMapFile maps Jira users to Cliq users (IDENTITY_MAP_FILE).
+	IdentityMapFile string
+	// CliqUsersAPI is the Cliq users API URL used to match Jira users to
+	// Cliq users by email (CLIQ_USERS_API), called with CliqOAuthToken
+	// (CLIQ_OAUTH_TOKEN).
+	CliqUsersAPI   string
+	CliqOAuthToken string
+	// Directory finds the Cliq users that messages mention.
+	Directory *identity.Directory
+	// Direct says whether people get direct messages.
+	Direct DirectConfig
+	// CommentExcerptLength is how many characters of a comment the
+	// notification shows, or 0 for all of it (COMMENT_EXCERPT_LENGTH).
+	CommentExcerptLength int
+}
+
+// Channel is a Cliq channel messages are sent to, with the settings they
+// are rendered with for it.
+type Channel = routing.Channel
+
+// DefaultChannel returns the channel set by CHANNEL_ENDPOINT,
+// CHANNEL_LOCALE, CHANNEL_TIMEZONE and CHANNEL_FILTER.
+func (c *Config) DefaultChannel() Channel {
+	return Channel{Name: routing.DefaultChannel, Endpoint: c.ChannelEndpoint, Locale: c.Locale, TimeZone: c.TimeZone, Filter: c.ChannelFilter}
+}
+
+// ValidationError lists every problem found while loading the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Load reads the configuration from the environment. When anything is wrong
+// it returns a *ValidationError describing all of the problems at once. The
+// configuration is returned even then, so that tools such as the render
+// command can use the settings that are valid.
+func Load() (*Config, error) {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	cfg := &Config{
+		CliqAPIToken:    os.Getenv("ZOHO_CLIQ_API_TOKEN"),
+		JiraURL:         strings.TrimRight(os.Getenv("JIRA_URL"), "/"),
+		ChannelEndpoint: os.Getenv("CHANNEL_ENDPOINT"),
+		SignatureMode:   os.Getenv("WEBHOOK_SIGNATURE_MODE"),
+		WebhookSecret:   os.Getenv("WEBHOOK_SECRET"),
+	}
+
+	if cfg.CliqAPIToken == "" {
+		addProblem("ZOHO_CLIQ_API_TOKEN is not set")
+	}
+	if err := checkURL(cfg.JiraURL); err != nil {
+		addProblem("JIRA_URL %v", err)
+	}
+
+	if cfg.SignatureMode == "" {
+		cfg.SignatureMode = SignatureOff
+	}
+	switch cfg.SignatureMode {
+	case SignatureOff:
+	case SignatureOptional, SignatureRequired:
+		if cfg.WebhookSecret == "" {
+			addProblem("WEBHOOK_SECRET is not set but WEBHOOK_SIGNATURE_MODE is %s", cfg.SignatureMode)
+		}
+	default:
+		addProblem("WEBHOOK_SIGNATURE_MODE must be off, optional or required, got %q", cfg.SignatureMode)
+	}
+
+	creds, err := parseCredentials(os.Getenv("LAMBDA_CREDS"))
+	if err != nil {
+		addProblem("%v", err)
+	}
+	if lambdaCred := os.Getenv("LAMBDA_CRED"); lambdaCred != "" {
+		creds = append(creds, Credential{ID: "default", Secret: lambdaCred})
+	}
+	cfg.Credentials = creds
+	if err == nil && len(creds) == 0 && cfg.SignatureMode != SignatureRequired {
+		addProblem("LAMBDA_CRED or LAMBDA_CREDS is not set")
+	}
+
+	deadLetter, err := LoadDeadLetter()
+	if err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			problems = append(problems, validationErr.Problems...)
+		}
+	}
+	cfg.DeadLetter = deadLetter
+
+	cfg.TemplateDir = os.Getenv("TEMPLATE_DIR")
+	cfg.Templates, err = templates.Load(cfg.TemplateDir)
+	if err != nil {
+		addProblem("templates: %v", err)
+	}
+
+	cfg.CatalogDir = os.Getenv("CATALOG_DIR")
+	cfg.Catalogs, err = i18n.Load(cfg.CatalogDir)
+	if err != nil {
+		addProblem("%v", err)
+		cfg.Catalogs = i18n.Default
+	}
+	cfg.Templates.UseCatalogs(cfg.Catalogs)
+	cfg.Locale = os.Getenv("CHANNEL_LOCALE")
+	if cfg.Locale == "" {
+		cfg.Locale = i18n.DefaultLocale
+	}
+	if !cfg.Catalogs.Has(cfg.Locale) {
+		addProblem("CHANNEL_LOCALE %q has no message catalog, expected one of %s", cfg.Locale, strings.Join(cfg.Catalogs.Locales(), ", "))
+	}
+
+	cfg.TimeZone = os.Getenv("CHANNEL_TIMEZONE")
+	if _, err := time.LoadLocation(cfg.TimeZone); err != nil {
+		addProblem("CHANNEL_TIMEZONE must be an IANA time zone such as Europe/Berlin, got %q", cfg.TimeZone)
+	}
+
+	if filter := os.Getenv("CHANNEL_FILTER"); filter != "" {
+		cfg.ChannelFilter, err = jql.Parse(filter)
+		if err != nil {
+			addProblem("CHANNEL_FILTER: %v", err)
+		}
+	}
+
+	cfg.RoutesFile = os.Getenv("ROUTES_FILE")
+	cfg.Routes, err = routing.Load(cfg.RoutesFile, cfg.DefaultChannel())
+	if err != nil {
+		addProblem("%v", err)
+	}
+	cfg.DeliveryConcurrency = DefaultDeliveryConcurrency
+	if concurrency := os.Getenv("DELIVERY_CONCURRENCY"); concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			addProblem("DELIVERY_CONCURRENCY must be a whole number of at least 1, got %q", concurrency)
+		} else {
+			cfg.DeliveryConcurrency = n
+		}
+	}
+	cfg.DeliveryTimeout = DefaultDeliveryTimeout
+	if timeout := os.Getenv("DELIVERY_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			addProblem("DELIVERY_TIMEOUT must be a duration such as 20s, got %q", timeout)
+		} else {
+			cfg.DeliveryTimeout = d
+		}
+	}
+
+	cfg.RulesFile = os.Getenv("RULES_FILE")
+	cfg.Rules, err = rules.Load(cfg.RulesFile)
+	if err != nil {
+		addProblem("%v", err)
+	}
+	var reroutes []routing.Route
+	for _, r := range cfg.Rules.Reroutes() {
+		if r.Route != "" {
+			if _, ok := cfg.Routes.Named(r.Route); !ok {
+				addProblem("rule %q reroutes to unknown route %q", r.Name, r.Route)
+			}
+			continue
+		}
+		route, err := cfg.Routes.To(r.Name, r.Channels)
+		if err != nil {
+			addProblem("rule %q reroutes to %v", r.Name, err)
+		}
+		reroutes = append(reroutes, route)
+	}
+	for _, ch := range cfg.Routes.Used(reroutes...) {
+		// The channel from the environment has had its locale and zone checked
+		if ch == cfg.DefaultChannel() {
+			if err := checkURL(ch.Endpoint); err != nil {
+				addProblem("CHANNEL_ENDPOINT %v", err)
+			}
+			continue
+		}
+		name := fmt.Sprintf("channel %q", ch.Name)
+		if err := checkURL(ch.Endpoint); err != nil {
+			addProblem("%s endpoint %v", name, err)
+		}
+		if !cfg.Catalogs.Has(ch.Locale) {
+			addProblem("%s has locale %q, which has no message catalog", name, ch.Locale)
+		}
+		if _, err := time.LoadLocation(ch.TimeZone); err != nil {
+			addProblem("%s must have an IANA time zone such as Europe/Berlin, got %q", name, ch.TimeZone)
+		}
+	}
+
+	cfg.CardStyleFile = os.Getenv("CARD_STYLE_FILE")
+	cfg.CardIconThumbnails = os.Getenv("CARD_ICON_THUMBNAILS") != "off"
+	styles, err := cardstyle.Load(cfg.CardStyleFile)
+	if err != nil {
+		addProblem("%v", err)
+	}
+	if cfg.CardIconThumbnails {
+		styles.Icons = cardstyle.NewIconChecker()
+	}
+	cfg.Templates.UseStyles(styles)
+
+	slides, slideProblems := loadSlides()
+	problems = append(problems, slideProblems...)
+	cfg.Slides = slides
+
+	cfg.IdentityMapFile = os.Getenv("IDENTITY_MAP_FILE")
+	cfg.CliqUsersAPI = os.Getenv("CLIQ_USERS_API")
+	cfg.CliqOAuthToken = os.Getenv("CLIQ_OAUTH_TOKEN")
+	if cfg.CliqUsersAPI != "" {
+		if err := checkURL(cfg.CliqUsersAPI); err != nil {
+			addProblem("CLIQ_USERS_API %v", err)
+		}
+		if cfg.CliqOAuthToken == "" {
+			addProblem("CLIQ_OAUTH_TOKEN is not set but CLIQ_USERS_API is")
+		}
+	}
+	cfg.Directory, err = identity.Load(identity.Options{
+		File:     cfg.IdentityMapFile,
+		UsersAPI: cfg.CliqUsersAPI,
+		Token:    cfg.CliqOAuthToken,
+	})
+	if err != nil {
+		addProblem("%v", err)
+	}
+	cfg.Templates.UseDirectory(cfg.Directory)
+
+	dm, directProblems := loadDirect()
+	problems = append(problems, directProblems...)
+	if dm.Endpoint != "" && cfg.Directory == nil {
+		addProblem("DM_ENDPOINT is set but neither IDENTITY_MAP_FILE nor CLIQ_USERS_API is, so nobody can be messaged")
+	}
+	cfg.Direct = dm
+
+	cfg.CommentExcerptLength = DefaultCommentExcerptLength
+	if length := os.Getenv("COMMENT_EXCERPT_LENGTH"); length != "" {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 {
+			addProblem("COMMENT_EXCERPT_LENGTH must be a whole number of characters, got %q", length)
+		} else {
+			cfg.CommentExcerptLength = n
+		}
+	}
+
+	dedup, dedupProblems := loadDedup()
+	problems = append(problems, dedupProblems...)
+	cfg.Dedup = dedup
+
+	if len(problems) > 0 {
+		return cfg, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// checkURL reports why raw is not a usable absolute http(s) URL.
+func checkURL(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("is not set")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must be an http or https URL, got %q", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("has no host, got %q", raw)
+	}
+	return nil
+}
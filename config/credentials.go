@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Credential is one accepted value of the lamda-auth query parameter.
+type Credential struct {
+	// ID names the credential in logs. The secret itself is never logged.
+	ID     string
+	Secret string
+	// NotAfter is the last moment the credential is accepted. The zero value
+	// means it does not expire.
+	NotAfter time.Time
+}
+
+// parseCredentials reads a comma-separated list of "id:secret" or
+// "id:secret:not-after" entries. not-after is either a date (2006-01-02,
+// valid until the end of that day in UTC) or an RFC 3339 timestamp. The
+// secret may contain colons, except before something that reads as a date.
+// Errors name an entry by its position, never by its content, because the
+// content may be a secret.
+func parseCredentials(list string) ([]Credential, error) {
+	var creds []Credential
+	n := 0
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		n++
+		id, secret, _ := strings.Cut(entry, ":")
+		if id == "" || secret == "" {
+			return nil, fmt.Errorf("LAMBDA_CREDS entry %d must have the form id:secret[:not-after]", n)
+		}
+		cred := Credential{ID: id, Secret: secret}
+		if m := notAfterPattern.FindStringSubmatchIndex(secret); m != nil {
+			notAfter, err := parseNotAfter(secret[m[2]:])
+			if err != nil {
+				return nil, fmt.Errorf("LAMBDA_CREDS entry %d: %w", n, err)
+			}
+			cred.Secret, cred.NotAfter = secret[:m[0]], notAfter
+			if cred.Secret == "" {
+				return nil, fmt.Errorf("LAMBDA_CREDS entry %d must have the form id:secret[:not-after]", n)
+			}
+		}
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+// notAfterPattern finds the not-after date or timestamp that ends a secret.
+var notAfterPattern = regexp.MustCompile(`:(\d{4}-\d{2}-\d{2}(?:T.*)?)$`)
+
+func parseNotAfter(value string) (time.Time, error) {
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid not-after date")
+	}
+	return t, nil
+}
+
+// MatchCredential returns the credential whose secret equals value. Every
+// credential is compared in constant time so the response time does not
+// reveal which one, if any, was close. When several credentials share the
+// secret, as they do while one replaces another, an unexpired one is
+// preferred. expired is true when value matched only credentials that are
+// past their NotAfter date.
+func MatchCredential(creds []Credential, value string, now time.Time) (match Credential, ok bool, expired bool) {
+	valid, stale := -1, -1
+	for i, cred := range creds {
+		if subtle.ConstantTimeCompare([]byte(cred.Secret), []byte(value)) != 1 {
+			continue
+		}
+		switch {
+		case !cred.NotAfter.IsZero() && now.After(cred.NotAfter):
+			if stale < 0 {
+				stale = i
+			}
+		case valid < 0:
+			valid = i
+		}
+	}
+	switch {
+	case valid >= 0:
+		return creds[valid], true, false
+	case stale >= 0:
+		return creds[stale], false, true
+	}
+	return Credential{}, false, false
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCredentials(t *testing.T) {
+	tests := []struct {
+		list string
+		want string
+	}{
+		{"", ""},
+		{"ci:s3cret", "ci:s3cret:-"},
+		{" ci:s3cret , jira:other ", "ci:s3cret:- jira:other:-"},
+		{"old:s3cret:2026-10-31", "old:s3cret:2026-10-31T23:59:59Z"},
+		{"old:s3cret:2026-10-31T12:00:00+02:00", "old:s3cret:2026-10-31T10:00:00Z"},
+		{"ci:a:b:c", "ci:a:b:c:-"},
+		{"ci:tok:2026-10-31", "ci:tok:2026-10-31T23:59:59Z"},
+	}
+	for _, tt := range tests {
+		creds, err := parseCredentials(tt.list)
+		if err != nil {
+			t.Errorf("parseCredentials(%q): %v", tt.list, err)
+			continue
+		}
+		var got []string
+		for _, c := range creds {
+			notAfter := "-"
+			if !c.NotAfter.IsZero() {
+				notAfter = c.NotAfter.UTC().Truncate(time.Second).Format(time.RFC3339)
+			}
+			got = append(got, c.ID+":"+c.Secret+":"+notAfter)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("parseCredentials(%q) = %s, want %s", tt.list, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestParseCredentialsErrors(t *testing.T) {
+	for _, list := range []string{"nosecret", "ci:", ":s3cret", "ci:s3cret,broken", "ci::2026-10-31", "ci:s3cret:2026-13-45"} {
+		_, err := parseCredentials(list)
+		if err == nil {
+			t.Errorf("parseCredentials(%q) succeeded", list)
+			continue
+		}
+		if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("parseCredentials(%q) error %q shows the secret", list, err)
+		}
+	}
+}
+
+func TestMatchCredential(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		creds   []Credential
+		value   string
+		id      string
+		ok      bool
+		expired bool
+	}{
+		{"no credentials", nil, "s3cret", "", false, false},
+		{"match", []Credential{{ID: "a", Secret: "other"}, {ID: "b", Secret: "s3cret"}}, "s3cret", "b", true, false},
+		{"no match", []Credential{{ID: "a", Secret: "s3cret"}}, "s3cre", "", false, false},
+		{"empty value", []Credential{{ID: "a", Secret: "s3cret"}}, "", "", false, false},
+		{"not yet expired", []Credential{{ID: "a", Secret: "s3cret", NotAfter: future}}, "s3cret", "a", true, false},
+		{"expired", []Credential{{ID: "a", Secret: "s3cret", NotAfter: past}}, "s3cret", "a", false, true},
+
+		// The same secret listed twice, while one entry replaces the other
+		{"expired duplicate first", []Credential{{ID: "old", Secret: "s3cret", NotAfter: past}, {ID: "new", Secret: "s3cret"}}, "s3cret", "new", true, false},
+		{"expired duplicate last", []Credential{{ID: "new", Secret: "s3cret", NotAfter: future}, {ID: "old", Secret: "s3cret", NotAfter: past}}, "s3cret", "new", true, false},
+		{"both valid", []Credential{{ID: "first", Secret: "s3cret", NotAfter: future}, {ID: "second", Secret: "s3cret"}}, "s3cret", "first", true, false},
+		{"both expired", []Credential{{ID: "first", Secret: "s3cret", NotAfter: past}, {ID: "second", Secret: "s3cret", NotAfter: past}}, "s3cret", "first", false, true},
+	}
+	for _, tt := range tests {
+		match, ok, expired := MatchCredential(tt.creds, tt.value, now)
+		if match.ID != tt.id || ok != tt.ok || expired != tt.expired {
+			t.Errorf("%s: MatchCredential = %q, %v, %v, want %q, %v, %v", tt.name, match.ID, ok, expired, tt.id, tt.ok, tt.expired)
+		}
+	}
+}
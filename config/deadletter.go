@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Values accepted by DEAD_LETTER_STORE.
+const (
+	// DeadLetterNone drops messages that could not be delivered.
+	DeadLetterNone = "none"
+	// DeadLetterFile keeps them as JSON files in DeadLetterConfig.Dir.
+	DeadLetterFile = "file"
+	// DeadLetterS3 keeps them as objects in an S3-compatible bucket.
+	DeadLetterS3 = "s3"
+)
+
+// DeadLetterConfig says where messages that could not be delivered to Cliq
+// are stored for the replay command.
+type DeadLetterConfig struct {
+	// Store is DeadLetterNone, DeadLetterFile or DeadLetterS3 (DEAD_LETTER_STORE).
+	Store string
+	// Dir is the directory of the file store (DEAD_LETTER_DIR).
+	Dir string
+	// S3Bucket is the bucket of the S3 store (DEAD_LETTER_S3_BUCKET).
+	S3Bucket string
+	// S3Prefix is prepended to every object key (DEAD_LETTER_S3_PREFIX).
+	S3Prefix string
+	// S3Region is the bucket's region (DEAD_LETTER_S3_REGION, or AWS_REGION).
+	S3Region string
+	// S3Endpoint points the store at an S3-compatible service such as MinIO
+	// instead of AWS (DEAD_LETTER_S3_ENDPOINT). Objects are then addressed
+	// path-style.
+	S3Endpoint string
+}
+
+// LoadDeadLetter reads only the dead-letter settings. The replay command uses
+// it so that it can run without the rest of the Lambda's configuration.
+func LoadDeadLetter() (DeadLetterConfig, error) {
+	var problems []string
+	dl := DeadLetterConfig{
+		Store:      os.Getenv("DEAD_LETTER_STORE"),
+		Dir:        os.Getenv("DEAD_LETTER_DIR"),
+		S3Bucket:   os.Getenv("DEAD_LETTER_S3_BUCKET"),
+		S3Prefix:   os.Getenv("DEAD_LETTER_S3_PREFIX"),
+		S3Region:   os.Getenv("DEAD_LETTER_S3_REGION"),
+		S3Endpoint: os.Getenv("DEAD_LETTER_S3_ENDPOINT"),
+	}
+	if dl.Store == "" {
+		dl.Store = DeadLetterNone
+	}
+	if dl.S3Region == "" {
+		dl.S3Region = os.Getenv("AWS_REGION")
+	}
+
+	switch dl.Store {
+	case DeadLetterNone:
+	case DeadLetterFile:
+		if dl.Dir == "" {
+			problems = append(problems, "DEAD_LETTER_DIR is not set but DEAD_LETTER_STORE is file")
+		}
+	case DeadLetterS3:
+		if dl.S3Bucket == "" {
+			problems = append(problems, "DEAD_LETTER_S3_BUCKET is not set but DEAD_LETTER_STORE is s3")
+		}
+		if dl.S3Region == "" {
+			problems = append(problems, "DEAD_LETTER_S3_REGION or AWS_REGION is not set but DEAD_LETTER_STORE is s3")
+		}
+		if dl.S3Endpoint != "" {
+			if err := checkURL(dl.S3Endpoint); err != nil {
+				problems = append(problems, fmt.Sprintf("DEAD_LETTER_S3_ENDPOINT %v", err))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DEAD_LETTER_STORE must be none, file or s3, got %q", dl.Store))
+	}
+
+	if len(problems) > 0 {
+		return dl, &ValidationError{Problems: problems}
+	}
+	return dl, nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Values accepted by DEDUP_STORE.
+const (
+	// DedupNone processes every delivery, duplicates included.
+	DedupNone = "none"
+	// DedupMemory remembers events in the memory of the running instance.
+	DedupMemory = "memory"
+	// DedupFile remembers events as files in DedupConfig.Dir.
+	DedupFile = "file"
+	// DedupDynamoDB remembers events in a DynamoDB-compatible table.
+	DedupDynamoDB = "dynamodb"
+)
+
+// DefaultDedupTTL is how long an event is remembered when DEDUP_TTL is not set.
+const DefaultDedupTTL = 24 * time.Hour
+
+// DefaultDedupLease is how long an event that is still being processed is
+// claimed when DEDUP_LEASE is not set.
+const DefaultDedupLease = 5 * time.Minute
+
+// DedupConfig says where processed webhook events are remembered so that
+// Jira's retries and duplicate deliveries are skipped.
+type DedupConfig struct {
+	// Store is DedupNone, DedupMemory, DedupFile or DedupDynamoDB (DEDUP_STORE).
+	Store string
+	// TTL is how long an event is remembered (DEDUP_TTL).
+	TTL time.Duration
+	// Lease is how long an event is claimed while it is processed
+	// (DEDUP_LEASE). Once it has been processed the claim lasts TTL.
+	Lease time.Duration
+	// Dir is the directory of the file store (DEDUP_DIR).
+	Dir string
+	// DynamoDBTable is the table of the DynamoDB store (DEDUP_DYNAMODB_TABLE).
+	// Its partition key must be a string attribute named "id".
+	DynamoDBTable string
+	// DynamoDBRegion is the table's region (DEDUP_DYNAMODB_REGION, or AWS_REGION).
+	DynamoDBRegion string
+	// DynamoDBEndpoint points the store at a DynamoDB-compatible service such
+	// as DynamoDB Local instead of AWS (DEDUP_DYNAMODB_ENDPOINT).
+	DynamoDBEndpoint string
+}
+
+func loadDedup() (DedupConfig, []string) {
+	var problems []string
+	dd := DedupConfig{
+		Store:            os.Getenv("DEDUP_STORE"),
+		TTL:              DefaultDedupTTL,
+		Lease:            DefaultDedupLease,
+		Dir:              os.Getenv("DEDUP_DIR"),
+		DynamoDBTable:    os.Getenv("DEDUP_DYNAMODB_TABLE"),
+		DynamoDBRegion:   os.Getenv("DEDUP_DYNAMODB_REGION"),
+		DynamoDBEndpoint: os.Getenv("DEDUP_DYNAMODB_ENDPOINT"),
+	}
+	if dd.Store == "" {
+		dd.Store = DedupMemory
+	}
+	if dd.DynamoDBRegion == "" {
+		dd.DynamoDBRegion = os.Getenv("AWS_REGION")
+	}
+	if ttl := os.Getenv("DEDUP_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			problems = append(problems, fmt.Sprintf("DEDUP_TTL must be a positive duration such as 24h, got %q", ttl))
+		} else {
+			dd.TTL = d
+		}
+	}
+	if lease := os.Getenv("DEDUP_LEASE"); lease != "" {
+		d, err := time.ParseDuration(lease)
+		if err != nil || d <= 0 {
+			problems = append(problems, fmt.Sprintf("DEDUP_LEASE must be a positive duration such as 5m, got %q", lease))
+		} else {
+			dd.Lease = d
+		}
+	}
+
+	switch dd.Store {
+	case DedupNone, DedupMemory:
+	case DedupFile:
+		if dd.Dir == "" {
+			problems = append(problems, "DEDUP_DIR is not set but DEDUP_STORE is file")
+		}
+	case DedupDynamoDB:
+		if dd.DynamoDBTable == "" {
+			problems = append(problems, "DEDUP_DYNAMODB_TABLE is not set but DEDUP_STORE is dynamodb")
+		}
+		if dd.DynamoDBRegion == "" {
+			problems = append(problems, "DEDUP_DYNAMODB_REGION or AWS_REGION is not set but DEDUP_STORE is dynamodb")
+		}
+		if dd.DynamoDBEndpoint != "" {
+			if err := checkURL(dd.DynamoDBEndpoint); err != nil {
+				problems = append(problems, fmt.Sprintf("DEDUP_DYNAMODB_ENDPOINT %v", err))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DEDUP_STORE must be none, memory, file or dynamodb, got %q", dd.Store))
+	}
+	return dd, problems
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"zogoapps/direct"
+)
+
+// userPlaceholder stands for the Cliq user ID in DM_ENDPOINT.
+const userPlaceholder = "{user}"
+
+// DirectConfig says whether people get direct messages about their issues.
+type DirectConfig struct {
+	// Endpoint is the Cliq message-to-user API URL with {user} in place of
+	// the Cliq user ID (DM_ENDPOINT). Direct messages are off when it is
+	// empty.
+	Endpoint string
+	// OptOut holds the users who do not want direct messages (DM_OPT_OUT).
+	OptOut direct.OptOut
+}
+
+func loadDirect() (DirectConfig, []string) {
+	var problems []string
+	dc := DirectConfig{
+		Endpoint: os.Getenv("DM_ENDPOINT"),
+		OptOut:   direct.NewOptOut(strings.Split(os.Getenv("DM_OPT_OUT"), ",")),
+	}
+	if dc.Endpoint == "" {
+		return dc, nil
+	}
+	if !strings.Contains(dc.Endpoint, userPlaceholder) {
+		problems = append(problems, fmt.Sprintf("DM_ENDPOINT must contain %s where the Cliq user ID goes, got %q", userPlaceholder, dc.Endpoint))
+	}
+	if err := checkURL(strings.ReplaceAll(dc.Endpoint, userPlaceholder, "0")); err != nil {
+		problems = append(problems, fmt.Sprintf("DM_ENDPOINT %v", err))
+	}
+	return dc, problems
+}
+
+// DirectChannel returns the channel of a direct message to r. It is written
+// in CHANNEL_LOCALE and shows times in the recipient's own time zone.
+func (c *Config) DirectChannel(r direct.Recipient) Channel {
+	name := r.DisplayName
+	if name == "" {
+		name = r.CliqID
+	}
+	timeZone := r.TimeZone
+	if timeZone == "" {
+		timeZone = c.TimeZone
+	}
+	return Channel{
+		Name:     "dm:" + name,
+		Endpoint: strings.ReplaceAll(c.Direct.Endpoint, userPlaceholder, url.PathEscape(r.CliqID)),
+		Locale:   c.Locale,
+		TimeZone: timeZone,
+	}
+}
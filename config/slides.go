@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"zogoapps/templates"
+)
+
+// loadSlides reads SLIDES_<NAME> for each template, for example
+// SLIDES_ISSUE_UPDATED=fields,changes. "none" attaches no slides, and a
+// template whose variable is not set gets templates.DefaultSlides.
+func loadSlides() (map[string][]string, []string) {
+	var problems []string
+	slides := map[string][]string{}
+	for _, name := range templates.Names {
+		envName := "SLIDES_" + strings.ToUpper(name)
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			slides[name] = templates.DefaultSlides[name]
+			continue
+		}
+		kinds := []string{}
+		for _, kind := range strings.Split(value, ",") {
+			switch kind = strings.TrimSpace(kind); kind {
+			case "", "none":
+			case templates.SlideFields, templates.SlideChanges:
+				kinds = append(kinds, kind)
+			default:
+				problems = append(problems, fmt.Sprintf("%s must list fields, changes or none, got %q", envName, kind))
+			}
+		}
+		slides[name] = kinds
+	}
+	return slides, problems
+}
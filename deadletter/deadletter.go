@@ -0,0 +1,98 @@
+// Package deadletter keeps messages that could not be delivered to Zoho Cliq,
+// together with the Jira payload they were rendered from, so that they can be
+// inspected and sent again with the replay command.
+package deadletter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+)
+
+// ErrNotFound is returned by Get and Delete for an unknown entry ID.
+var ErrNotFound = errors.New("dead-letter entry not found")
+
+// Entry is one message that could not be delivered.
+type Entry struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Event is the webhook event the message was rendered for.
+	Event string `json:"event"`
+	// Endpoint is the Cliq channel the message was meant for. It never
+	// includes the API token.
+	Endpoint string `json:"endpoint"`
+	// Payload is the original Jira webhook body.
+	Payload json.RawMessage `json:"payload"`
+	// Message is the rendered Cliq message.
+	Message cliq.Message `json:"message"`
+	// Error is the reason the last delivery attempt failed.
+	Error string `json:"error"`
+	// Temporary is set when the failure was temporary. Jira was asked to
+	// redeliver the webhook then, so the message may have arrived since.
+	Temporary bool `json:"temporary,omitempty"`
+}
+
+// Store saves and loads dead-letter entries.
+type Store interface {
+	// Put saves e. If e.ID is empty a new ID is assigned.
+	Put(ctx context.Context, e *Entry) error
+	// List returns every entry, oldest first.
+	List(ctx context.Context) ([]Entry, error)
+	// Get returns the entry with the given ID.
+	Get(ctx context.Context, id string) (*Entry, error)
+	// Delete removes the entry with the given ID.
+	Delete(ctx context.Context, id string) error
+}
+
+// Open returns the store described by cfg, or nil when dead-lettering is
+// disabled.
+func Open(cfg config.DeadLetterConfig) (Store, error) {
+	switch cfg.Store {
+	case config.DeadLetterNone, "":
+		return nil, nil
+	case config.DeadLetterFile:
+		return &FileStore{Dir: cfg.Dir}, nil
+	case config.DeadLetterS3:
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unknown dead-letter store %q", cfg.Store)
+	}
+}
+
+// NewID returns an entry ID that sorts by creation time.
+func NewID(now time.Time) string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return now.UTC().Format("20060102T150405.000Z") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// prepare fills in the ID and creation time of a new entry.
+func prepare(e *Entry) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	if e.ID == "" {
+		e.ID = NewID(e.CreatedAt)
+	}
+}
+
+// validID guards the stores against IDs that would escape their directory
+// or prefix.
+func validID(id string) error {
+	if id == "" {
+		return errors.New("empty dead-letter entry ID")
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return fmt.Errorf("invalid dead-letter entry ID %q", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore keeps each entry as a JSON file in Dir.
+type FileStore struct {
+	Dir string
+}
+
+// Put writes e to <Dir>/<ID>.json.
+func (s *FileStore) Put(ctx context.Context, e *Entry) error {
+	prepare(e)
+	if err := validID(e.ID); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("creating dead-letter directory: %w", err)
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding dead-letter entry: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves half an entry
+	tmp := s.path(e.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing dead-letter entry: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(e.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing dead-letter entry: %w", err)
+	}
+	return nil
+}
+
+// List reads every entry in Dir.
+func (s *FileStore) List(ctx context.Context) ([]Entry, error) {
+	files, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing dead-letter entries: %w", err)
+	}
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		e, err := s.Get(ctx, strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Get reads the entry with the given ID.
+func (s *FileStore) Get(ctx context.Context, id string) (*Entry, error) {
+	if err := validID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading dead-letter entry: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decoding dead-letter entry %s: %w", id, err)
+	}
+	return &e, nil
+}
+
+// Delete removes the entry with the given ID.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if err := validID(id); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
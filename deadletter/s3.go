@@ -0,0 +1,222 @@
+package deadletter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"zogoapps/awsauth"
+	"zogoapps/config"
+)
+
+// S3Store keeps each entry as a JSON object in an S3-compatible bucket.
+type S3Store struct {
+	Bucket string
+	Prefix string
+	// Endpoint is the base URL of an S3-compatible service. When empty the
+	// AWS endpoint for the signer's region is used.
+	Endpoint   string
+	Signer     awsauth.Signer
+	HTTPClient *http.Client
+}
+
+// NewS3Store returns a store for the bucket in cfg, signed with the AWS
+// credentials from the environment.
+func NewS3Store(cfg config.DeadLetterConfig) (*S3Store, error) {
+	creds, err := awsauth.CredentialsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("dead-letter S3 store: %w", err)
+	}
+	return &S3Store{
+		Bucket:   cfg.S3Bucket,
+		Prefix:   cfg.S3Prefix,
+		Endpoint: cfg.S3Endpoint,
+		Signer:   awsauth.Signer{Credentials: creds, Region: cfg.S3Region, Service: "s3"},
+	}, nil
+}
+
+// Put uploads e as <Prefix><ID>.json.
+func (s *S3Store) Put(ctx context.Context, e *Entry) error {
+	prepare(e)
+	if err := validID(e.ID); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding dead-letter entry: %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.key(e.ID), nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "storing dead-letter entry")
+}
+
+// listBucketResult is the part of the ListObjectsV2 reply we need.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List downloads every entry under Prefix.
+func (s *S3Store) List(ctx context.Context) ([]Entry, error) {
+	var ids []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		if err := checkStatus(resp, "listing dead-letter entries"); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding dead-letter listing: %w", err)
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, s.Prefix)
+			if strings.HasSuffix(name, ".json") && !strings.Contains(name, "/") {
+				ids = append(ids, strings.TrimSuffix(name, ".json"))
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Strings(ids)
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		e, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// Get downloads the entry with the given ID.
+func (s *S3Store) Get(ctx context.Context, id string) (*Entry, error) {
+	if err := validID(id); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.key(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err := checkStatus(resp, "reading dead-letter entry"); err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return nil, fmt.Errorf("decoding dead-letter entry %s: %w", id, err)
+	}
+	return &e, nil
+}
+
+// Delete removes the entry with the given ID. S3 does not report whether the
+// object existed, so Delete never returns ErrNotFound.
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	if err := validID(id); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.key(id), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "deleting dead-letter entry")
+}
+
+func (s *S3Store) key(id string) string {
+	return s.Prefix + id + ".json"
+}
+
+// do sends a signed request for the object key, or for the bucket itself
+// when key is empty.
+func (s *S3Store) do(ctx context.Context, method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating S3 request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.Signer.Sign(req, body, time.Now())
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// objectURL addresses the bucket virtual-host style on AWS and path-style on
+// a custom endpoint.
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	path := ""
+	if key != "" {
+		var escaped []string
+		for _, part := range strings.Split(key, "/") {
+			escaped = append(escaped, awsauth.Escape(part))
+		}
+		path = "/" + strings.Join(escaped, "/")
+	}
+
+	if s.Endpoint == "" {
+		if path == "" {
+			path = "/"
+		}
+		return url.Parse("https://" + s.Bucket + ".s3." + s.Signer.Region + ".amazonaws.com" + path)
+	}
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/") + "/" + awsauth.Escape(s.Bucket) + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	return u, nil
+}
+
+func checkStatus(resp *http.Response, action string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s: S3 answered %s: %s", action, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,41 @@
+// Package dedup remembers which webhook events have already been processed
+// so that Jira's retries and duplicate deliveries are skipped.
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zogoapps/config"
+)
+
+// Store remembers processed event keys for a limited time.
+type Store interface {
+	// Claim records key and reports whether it was new. A key claimed less
+	// than ttl ago is a duplicate and Claim returns false.
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Extend keeps a claimed key until ttl from now, so that a short claim
+	// taken while an event is processed can last once it is done.
+	Extend(ctx context.Context, key string, ttl time.Duration) error
+	// Release forgets key, so that a redelivery of an event that could not
+	// be processed is not mistaken for a duplicate.
+	Release(ctx context.Context, key string) error
+}
+
+// Open returns the store described by cfg, or nil when deduplication is
+// disabled.
+func Open(cfg config.DedupConfig) (Store, error) {
+	switch cfg.Store {
+	case config.DedupNone, "":
+		return nil, nil
+	case config.DedupMemory:
+		return NewMemoryStore(), nil
+	case config.DedupFile:
+		return &FileStore{Dir: cfg.Dir}, nil
+	case config.DedupDynamoDB:
+		return NewDynamoDBStore(cfg)
+	default:
+		return nil, fmt.Errorf("unknown dedup store %q", cfg.Store)
+	}
+}
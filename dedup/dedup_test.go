@@ -0,0 +1,166 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zogoapps/awsauth"
+)
+
+// testStore checks the behaviour every Store shares. Expiry is checked with
+// two-second TTLs, because the file and DynamoDB stores round to Unix
+// seconds.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := fmt.Sprintf("webhook:test-%d", time.Now().UnixNano())
+
+	claim := func(key string, ttl time.Duration, want bool) {
+		t.Helper()
+		got, err := s.Claim(ctx, key, ttl)
+		if err != nil {
+			t.Fatalf("Claim(%q): %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("Claim(%q) = %v, want %v", key, got, want)
+		}
+	}
+
+	t.Run("claim", func(t *testing.T) {
+		claim(key, time.Hour, true)
+		claim(key, time.Hour, false)
+		claim(key+"/other", time.Hour, true)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		if err := s.Release(ctx, key); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		claim(key, time.Hour, true)
+		if err := s.Release(ctx, key+"/never-claimed"); err != nil {
+			t.Fatalf("Release of an unknown key: %v", err)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		expiring := key + "/expiring"
+		claim(expiring, 2*time.Second, true)
+		claim(expiring, 2*time.Second, false)
+		time.Sleep(2100 * time.Millisecond)
+		claim(expiring, time.Hour, true)
+	})
+
+	t.Run("extend", func(t *testing.T) {
+		leased := key + "/leased"
+		claim(leased, 2*time.Second, true)
+		if err := s.Extend(ctx, leased, time.Hour); err != nil {
+			t.Fatalf("Extend: %v", err)
+		}
+		time.Sleep(2100 * time.Millisecond)
+		claim(leased, time.Hour, false)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, &FileStore{Dir: t.TempDir()})
+}
+
+func TestFileStoreConcurrentClaims(t *testing.T) {
+	ctx := context.Background()
+	s := &FileStore{Dir: t.TempDir()}
+
+	// claimAll claims key from many goroutines at once and returns how many
+	// of them got it.
+	claimAll := func(key string) int32 {
+		var wins int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				ok, err := s.Claim(ctx, key, time.Hour)
+				if err != nil {
+					t.Errorf("Claim: %v", err)
+				}
+				if ok {
+					atomic.AddInt32(&wins, 1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		return wins
+	}
+
+	for round := 0; round < 50; round++ {
+		key := fmt.Sprintf("webhook:race-%d", round)
+		if wins := claimAll(key); wins != 1 {
+			t.Fatalf("round %d: %d claims of a new key succeeded, want 1", round, wins)
+		}
+		// Let the claim expire and race for it again
+		if err := s.Extend(ctx, key, -time.Hour); err != nil {
+			t.Fatalf("Extend: %v", err)
+		}
+		if wins := claimAll(key); wins != 1 {
+			t.Fatalf("round %d: %d claims of an expired key succeeded, want 1", round, wins)
+		}
+	}
+
+	// Temporary files are cleaned up
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 50 {
+		t.Errorf("%d files left in the directory, want the 50 claims", len(entries))
+	}
+}
+
+// TestDynamoDBStore runs against a DynamoDB-compatible service such as
+// DynamoDB Local, started with
+//
+//	docker run -p 8000:8000 amazon/dynamodb-local
+//
+// and named by DEDUP_DYNAMODB_ENDPOINT, for example http://localhost:8000.
+// The test creates and deletes its own table.
+func TestDynamoDBStore(t *testing.T) {
+	endpoint := os.Getenv("DEDUP_DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DEDUP_DYNAMODB_ENDPOINT is not set")
+	}
+	s := &DynamoDBStore{
+		Table:    fmt.Sprintf("dedup-test-%d", time.Now().UnixNano()),
+		Endpoint: endpoint,
+		Signer: awsauth.Signer{
+			Credentials: awsauth.Credentials{AccessKeyID: "test", SecretAccessKey: "test"},
+			Region:      "us-east-1",
+			Service:     "dynamodb",
+		},
+	}
+	ctx := context.Background()
+	err := s.call(ctx, "CreateTable", map[string]interface{}{
+		"TableName":            s.Table,
+		"AttributeDefinitions": []map[string]string{{"AttributeName": "id", "AttributeType": "S"}},
+		"KeySchema":            []map[string]string{{"AttributeName": "id", "KeyType": "HASH"}},
+		"BillingMode":          "PAY_PER_REQUEST",
+	})
+	if err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.call(ctx, "DeleteTable", map[string]string{"TableName": s.Table}); err != nil {
+			t.Errorf("deleting table: %v", err)
+		}
+	})
+	testStore(t, s)
+}
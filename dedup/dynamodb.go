@@ -0,0 +1,144 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zogoapps/awsauth"
+	"zogoapps/config"
+)
+
+// DynamoDBStore keeps keys as items in a DynamoDB-compatible table. The
+// partition key is the string attribute "id", and "expiresAt" holds the
+// expiry in Unix seconds so that DynamoDB's TTL feature can remove old items.
+type DynamoDBStore struct {
+	Table string
+	// Endpoint is the base URL of the service. When empty the AWS endpoint
+	// for the signer's region is used. Point it at DynamoDB Local to test.
+	Endpoint   string
+	Signer     awsauth.Signer
+	HTTPClient *http.Client
+}
+
+// NewDynamoDBStore returns a store for the table in cfg, signed with the AWS
+// credentials from the environment.
+func NewDynamoDBStore(cfg config.DedupConfig) (*DynamoDBStore, error) {
+	creds, err := awsauth.CredentialsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("dedup DynamoDB store: %w", err)
+	}
+	return &DynamoDBStore{
+		Table:    cfg.DynamoDBTable,
+		Endpoint: cfg.DynamoDBEndpoint,
+		Signer:   awsauth.Signer{Credentials: creds, Region: cfg.DynamoDBRegion, Service: "dynamodb"},
+	}, nil
+}
+
+// attributeValue is DynamoDB's JSON encoding of a single value.
+type attributeValue struct {
+	S string `json:"S,omitempty"`
+	N string `json:"N,omitempty"`
+}
+
+// Claim writes the item unless an unexpired item with the same id exists.
+func (s *DynamoDBStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	request := map[string]interface{}{
+		"TableName": s.Table,
+		"Item": map[string]attributeValue{
+			"id":        {S: key},
+			"expiresAt": {N: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+		},
+		// DynamoDB's TTL deletes expired items lazily, so check the expiry too
+		"ConditionExpression": "attribute_not_exists(id) OR expiresAt <= :now",
+		"ExpressionAttributeValues": map[string]attributeValue{
+			":now": {N: strconv.FormatInt(now.Unix(), 10)},
+		},
+	}
+	err := s.call(ctx, "PutItem", request)
+	var apiErr *dynamoDBError
+	if errors.As(err, &apiErr) && strings.HasSuffix(apiErr.Type, "ConditionalCheckFailedException") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Extend overwrites the item with a later expiry.
+func (s *DynamoDBStore) Extend(ctx context.Context, key string, ttl time.Duration) error {
+	return s.call(ctx, "PutItem", map[string]interface{}{
+		"TableName": s.Table,
+		"Item": map[string]attributeValue{
+			"id":        {S: key},
+			"expiresAt": {N: strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)},
+		},
+	})
+}
+
+// Release deletes the item.
+func (s *DynamoDBStore) Release(ctx context.Context, key string) error {
+	return s.call(ctx, "DeleteItem", map[string]interface{}{
+		"TableName": s.Table,
+		"Key":       map[string]attributeValue{"id": {S: key}},
+	})
+}
+
+// dynamoDBError is an error reply from the service.
+type dynamoDBError struct {
+	Status  string
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *dynamoDBError) Error() string {
+	return fmt.Sprintf("dynamodb: %s: %s: %s", e.Status, e.Type, e.Message)
+}
+
+// call invokes a DynamoDB API operation and discards its result.
+func (s *DynamoDBStore) call(ctx context.Context, operation string, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("dynamodb: encoding %s: %w", operation, err)
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://dynamodb." + s.Signer.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("dynamodb: creating %s request: %w", operation, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+operation)
+	s.Signer.Sign(req, body, time.Now())
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("dynamodb: %s failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	apiErr := &dynamoDBError{Status: resp.Status}
+	if err := json.Unmarshal(data, apiErr); err != nil {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
@@ -0,0 +1,145 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileStore keeps one file per key in Dir. The file is named after the
+// SHA-256 of the key and holds its expiry time in Unix seconds. Claims are
+// written to a temporary file and linked into place, which fails if the file
+// exists, so concurrent processes on the same host agree on which of them
+// claimed a key and never read a half-written claim.
+type FileStore struct {
+	Dir string
+}
+
+// Claim records key until ttl from now.
+func (s *FileStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return false, fmt.Errorf("creating dedup directory: %w", err)
+	}
+	now := time.Now()
+	path := s.path(key)
+	claimed, err := s.create(path, now.Add(ttl))
+	if claimed || err != nil {
+		return claimed, err
+	}
+	if !s.expired(path, now) {
+		return false, nil
+	}
+	// The previous claim has expired, so the key is new again. Remove it
+	// and create ours the same way, so that when several processes see it
+	// expire only one of them claims the key.
+	if err := s.removeExpired(path, now); err != nil {
+		return false, err
+	}
+	return s.create(path, now.Add(ttl))
+}
+
+// Extend keeps key until ttl from now.
+func (s *FileStore) Extend(ctx context.Context, key string, ttl time.Duration) error {
+	tmp, err := s.write(time.Now().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("extending dedup key: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("extending dedup key: %w", err)
+	}
+	return nil
+}
+
+// Release forgets key.
+func (s *FileStore) Release(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("releasing dedup key: %w", err)
+	}
+	return nil
+}
+
+// create stores a claim until expiry at path unless there is one already.
+func (s *FileStore) create(path string, expiry time.Time) (bool, error) {
+	tmp, err := s.write(expiry)
+	if err != nil {
+		return false, fmt.Errorf("claiming dedup key: %w", err)
+	}
+	defer os.Remove(tmp)
+	err = os.Link(tmp, path)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claiming dedup key: %w", err)
+	}
+	return true, nil
+}
+
+// removeExpired removes the expired claim at path. The claim is first moved
+// to a name of its own, so that if another process replaced it with a fresh
+// claim since it was read, that claim is put back rather than lost.
+func (s *FileStore) removeExpired(path string, now time.Time) error {
+	f, err := os.CreateTemp(s.Dir, ".expired-*")
+	if err != nil {
+		return fmt.Errorf("claiming dedup key: %w", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := os.Rename(path, f.Name()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("claiming dedup key: %w", err)
+	}
+	if !s.expired(f.Name(), now) {
+		// Put it back; if the link fails, yet another claim is in place
+		os.Link(f.Name(), path)
+	}
+	return nil
+}
+
+// write writes expiry to a new temporary file in Dir and returns its name.
+func (s *FileStore) write(expiry time.Time) (string, error) {
+	f, err := os.CreateTemp(s.Dir, ".claim-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(strconv.FormatInt(expiry.Unix(), 10))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// expired reports whether the claim stored at path is past its expiry. An
+// unreadable claim counts as expired.
+func (s *FileStore) expired(path string, now time.Time) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return true
+	}
+	expiry, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return true
+	}
+	return now.Unix() >= expiry
+}
+
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,59 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps keys in memory. On Lambda it only catches duplicates
+// that reach the same warm instance, which covers Jira's quick retries.
+type MemoryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{expires: map[string]time.Time{}, now: time.Now}
+}
+
+// Claim records key until ttl from now.
+func (s *MemoryStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if _, ok := s.expires[key]; ok {
+		return false, nil
+	}
+	s.expires[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Extend keeps key until ttl from now.
+func (s *MemoryStore) Extend(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires[key] = s.now().Add(ttl)
+	return nil
+}
+
+// Release forgets key.
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, key)
+	return nil
+}
+
+// expire drops every key that has outlived its TTL.
+func (s *MemoryStore) expire(now time.Time) {
+	for key, at := range s.expires {
+		if !now.Before(at) {
+			delete(s.expires, key)
+		}
+	}
+}
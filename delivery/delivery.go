@@ -0,0 +1,126 @@
+// Package delivery sends rendered messages to Zoho Cliq and keeps the ones
+// that could not be delivered in the dead-letter store.
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+	"zogoapps/deadletter"
+)
+
+// deadLetterTimeout bounds how long saving a failed message may take. It is
+// separate from the request context, which may already be past its deadline
+// after the retries.
+const deadLetterTimeout = 10 * time.Second
+
+// Target is a message to deliver to one channel.
+type Target struct {
+	Channel config.Channel
+	Message cliq.Message
+}
+
+// Result is the outcome of delivering to one channel.
+type Result struct {
+	Channel config.Channel
+	// StatusCode is the status of Cliq's last reply, or 0 if there was none.
+	StatusCode int
+	// Err is the reason the message could not be delivered.
+	Err error
+	// Duration is how long delivery took, retries included.
+	Duration time.Duration
+}
+
+// FanOut delivers each target's message to its channel. Up to
+// cfg.DeliveryConcurrency deliveries run at a time, and each is given the
+// channel's timeout, or else cfg.DeliveryTimeout, so that a slow channel
+// only holds up its own delivery. The results are in the order of targets.
+func FanOut(ctx context.Context, cfg *config.Config, event string, payload string, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	limit := cfg.DeliveryConcurrency
+	if limit < 1 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			timeout := t.Channel.Timeout
+			if timeout == 0 {
+				timeout = cfg.DeliveryTimeout
+			}
+			sendCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				sendCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			resp, err := Send(sendCtx, cfg, t.Channel, event, payload, t.Message)
+			results[i] = Result{Channel: t.Channel, Err: err, Duration: time.Since(start)}
+			if resp != nil {
+				results[i].StatusCode = resp.StatusCode
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// Send posts msg to the Cliq channel ch. If it cannot be delivered,
+// msg is saved to the dead-letter store together with the Jira payload it
+// was rendered from, and the delivery error is returned. A temporary failure
+// is saved as such, because Jira redelivers the webhook after one.
+func Send(ctx context.Context, cfg *config.Config, ch config.Channel, event string, payload string, msg cliq.Message) (*cliq.Response, error) {
+	resp, err := cliq.NewClient(ch.Endpoint, cfg.CliqAPIToken).Send(ctx, msg)
+	if err != nil {
+		saveDeadLetter(cfg, &deadletter.Entry{
+			Event:     event,
+			Endpoint:  ch.Endpoint,
+			Payload:   json.RawMessage(payload),
+			Message:   msg,
+			Error:     err.Error(),
+			Temporary: cliq.IsRetryable(err),
+		})
+		return resp, err
+	}
+
+	// Print the response status code
+	log.Printf("Response Status Code from channel %q: %d", ch.Name, resp.StatusCode)
+	return resp, nil
+}
+
+// saveDeadLetter stores e, logging rather than returning any error so that
+// the delivery error stays the one reported to Jira.
+func saveDeadLetter(cfg *config.Config, e *deadletter.Entry) {
+	store, err := deadletter.Open(cfg.DeadLetter)
+	if err != nil {
+		log.Printf("Error opening dead-letter store: %v", err)
+		return
+	}
+	if store == nil {
+		return
+	}
+	if !json.Valid(e.Payload) {
+		payload, _ := json.Marshal(string(e.Payload))
+		e.Payload = payload
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	if err := store.Put(ctx, e); err != nil {
+		log.Printf("Error saving message to the dead-letter store: %v", err)
+		return
+	}
+	log.Printf("Saved undelivered message as dead-letter entry %s", e.ID)
+}
@@ -0,0 +1,161 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+)
+
+// cliqServer answers every message with 200 after delay, and records the
+// most requests it had in flight at once.
+type cliqServer struct {
+	*httptest.Server
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	requests    int
+}
+
+func newCliqServer(t *testing.T, delay time.Duration) *cliqServer {
+	t.Helper()
+	s := &cliqServer{delay: delay}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.inFlight++
+		s.requests++
+		if s.inFlight > s.maxInFlight {
+			s.maxInFlight = s.inFlight
+		}
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+		time.Sleep(s.delay)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// blockingServer never answers until the test ends.
+func blockingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	return srv
+}
+
+func target(name string, endpoint string, timeout time.Duration) Target {
+	return Target{
+		Channel: config.Channel{Name: name, Endpoint: endpoint, Timeout: timeout},
+		Message: cliq.Message{Text: "PAY-1 created"},
+	}
+}
+
+func TestFanOutSlowChannel(t *testing.T) {
+	fast := newCliqServer(t, 0)
+	slow := blockingServer(t)
+	cfg := &config.Config{DeliveryConcurrency: 4, DeliveryTimeout: 5 * time.Second}
+	targets := []Target{
+		target("first", fast.URL, 0),
+		target("slow", slow.URL, 300*time.Millisecond),
+		target("second", fast.URL, 0),
+		target("third", fast.URL, 0),
+	}
+
+	start := time.Now()
+	results := FanOut(context.Background(), cfg, "jira:issue_created", `{}`, targets)
+	elapsed := time.Since(start)
+
+	if len(results) != len(targets) {
+		t.Fatalf("%d results for %d targets", len(results), len(targets))
+	}
+	for i, r := range results {
+		if r.Channel.Name != targets[i].Channel.Name {
+			t.Errorf("result %d is for %q, want %q", i, r.Channel.Name, targets[i].Channel.Name)
+		}
+		if r.Channel.Name == "slow" {
+			continue
+		}
+		if r.Err != nil || r.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d, error %v, want 200", r.Channel.Name, r.StatusCode, r.Err)
+		}
+		if r.Duration > 200*time.Millisecond {
+			t.Errorf("%s took %s, want it not to wait for the slow channel", r.Channel.Name, r.Duration)
+		}
+	}
+
+	// The slow channel gives up at its own timeout, not the default one
+	slowResult := results[1]
+	if slowResult.Err == nil || !cliq.IsRetryable(slowResult.Err) {
+		t.Errorf("slow: error %v, want a retryable timeout", slowResult.Err)
+	}
+	if slowResult.StatusCode != 0 {
+		t.Errorf("slow: status %d, want none", slowResult.StatusCode)
+	}
+	if slowResult.Duration < 300*time.Millisecond || slowResult.Duration > time.Second {
+		t.Errorf("slow: took %s, want its 300ms timeout", slowResult.Duration)
+	}
+	if elapsed > time.Second {
+		t.Errorf("FanOut took %s, want about the slow channel's timeout", elapsed)
+	}
+}
+
+func TestFanOutDefaultTimeout(t *testing.T) {
+	slow := blockingServer(t)
+	cfg := &config.Config{DeliveryConcurrency: 1, DeliveryTimeout: 200 * time.Millisecond}
+
+	results := FanOut(context.Background(), cfg, "jira:issue_created", `{}`, []Target{target("slow", slow.URL, 0)})
+	if results[0].Err == nil {
+		t.Fatal("delivery to a server that never answers succeeded")
+	}
+	if d := results[0].Duration; d < 200*time.Millisecond || d > time.Second {
+		t.Errorf("took %s, want the 200ms DeliveryTimeout", d)
+	}
+}
+
+func TestFanOutConcurrencyLimit(t *testing.T) {
+	srv := newCliqServer(t, 50*time.Millisecond)
+	cfg := &config.Config{DeliveryConcurrency: 2, DeliveryTimeout: 5 * time.Second}
+	var targets []Target
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		targets = append(targets, target(name, srv.URL, 0))
+	}
+
+	start := time.Now()
+	results := FanOut(context.Background(), cfg, "jira:issue_created", `{}`, targets)
+	elapsed := time.Since(start)
+
+	for i, r := range results {
+		if r.Channel.Name != targets[i].Channel.Name || r.Err != nil {
+			t.Errorf("result %d = %s, %v, want %s delivered", i, r.Channel.Name, r.Err, targets[i].Channel.Name)
+		}
+	}
+	if srv.requests != 6 {
+		t.Errorf("%d requests, want 6", srv.requests)
+	}
+	if srv.maxInFlight != 2 {
+		t.Errorf("%d deliveries ran at once, want the limit of 2", srv.maxInFlight)
+	}
+	// Six deliveries of 50ms, two at a time
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("FanOut took %s, want at least 150ms", elapsed)
+	}
+}
@@ -0,0 +1,153 @@
+// Package direct picks the people who get a Cliq direct message about an
+// event: the new assignee of an issue, and the assignee and reporter of an
+// issue someone commented on. Jira Cloud's comment webhooks carry no
+// reporter, so only the assignee hears about comments there. Nobody gets a
+// message about their own action.
+package direct
+
+import (
+	"strings"
+
+	"zogoapps/identity"
+)
+
+// Reasons a person gets a direct message.
+const (
+	ReasonAssigned  = "assigned"
+	ReasonCommented = "commented"
+)
+
+// User is a Jira user in a webhook body.
+type User struct {
+	AccountID   string
+	Name        string
+	Email       string
+	DisplayName string
+	TimeZone    string
+}
+
+// Recipient is a person who gets a direct message.
+type Recipient struct {
+	User
+	// CliqID is the Cliq user the message is sent to.
+	CliqID string
+	// Reason is ReasonAssigned or ReasonCommented.
+	Reason string
+}
+
+// OptOut is the set of users who do not want direct messages, by Jira
+// account ID, username or email, or by Cliq user ID.
+type OptOut map[string]bool
+
+// NewOptOut returns the opt-out set of the given users.
+func NewOptOut(users []string) OptOut {
+	o := OptOut{}
+	for _, u := range users {
+		if u = strings.ToLower(strings.TrimSpace(u)); u != "" {
+			o[u] = true
+		}
+	}
+	return o
+}
+
+// has reports whether any of the identifiers has opted out.
+func (o OptOut) has(ids ...string) bool {
+	for _, id := range ids {
+		if id != "" && o[strings.ToLower(id)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Recipients returns who gets a direct message about the event in a webhook
+// body decoded into maps. Users the directory cannot map to Cliq, users who
+// opted out and the user who caused the event are left out.
+func Recipients(payload map[string]interface{}, dir *identity.Directory, optOut OptOut) []Recipient {
+	fields := object(object(payload, "issue"), "fields")
+	var candidates []Recipient
+	switch reason := reasonOf(payload); reason {
+	case ReasonAssigned:
+		candidates = append(candidates, Recipient{User: userOf(object(fields, "assignee")), Reason: reason})
+	case ReasonCommented:
+		candidates = append(candidates,
+			Recipient{User: userOf(object(fields, "assignee")), Reason: reason},
+			Recipient{User: userOf(object(fields, "reporter")), Reason: reason},
+		)
+	default:
+		return nil
+	}
+
+	actor := userOf(object(payload, "user"))
+	if comment := object(payload, "comment"); comment != nil {
+		actor = userOf(object(comment, "author"))
+	}
+	actorID := dir.Lookup(actor.AccountID, actor.Name, actor.Email)
+
+	var recipients []Recipient
+	seen := map[string]bool{}
+	for _, r := range candidates {
+		if r.AccountID == "" && r.Name == "" && r.Email == "" {
+			continue
+		}
+		r.CliqID = dir.Lookup(r.AccountID, r.Name, r.Email)
+		if r.CliqID == "" || seen[r.CliqID] || r.CliqID == actorID || r.same(actor) {
+			continue
+		}
+		if optOut.has(r.AccountID, r.Name, r.Email, r.CliqID) {
+			continue
+		}
+		seen[r.CliqID] = true
+		recipients = append(recipients, r)
+	}
+	return recipients
+}
+
+// reasonOf tells why the event would warrant direct messages, or returns
+// "" when it does not. Issues are assigned when they are created with an
+// assignee or when the changelog changes the assignee. Comment edits do not
+// count.
+func reasonOf(payload map[string]interface{}) string {
+	event, _ := payload["webhookEvent"].(string)
+	eventType, _ := payload["issue_event_type_name"].(string)
+	switch {
+	case event == "comment_created":
+		return ReasonCommented
+	case event == "jira:issue_created" || eventType == "issue_assigned":
+		return ReasonAssigned
+	case event == "jira:issue_updated":
+		items, _ := object(payload, "changelog")["items"].([]interface{})
+		for _, item := range items {
+			if field, _ := item.(map[string]interface{})["field"].(string); strings.EqualFold(field, "assignee") {
+				return ReasonAssigned
+			}
+		}
+	}
+	return ""
+}
+
+// same reports whether u and other are the same Jira user.
+func (u User) same(other User) bool {
+	return (u.AccountID != "" && u.AccountID == other.AccountID) ||
+		(u.Name != "" && u.Name == other.Name) ||
+		(u.Email != "" && strings.EqualFold(u.Email, other.Email))
+}
+
+func userOf(m map[string]interface{}) User {
+	s := func(key string) string {
+		v, _ := m[key].(string)
+		return v
+	}
+	return User{
+		AccountID:   s("accountId"),
+		Name:        s("name"),
+		Email:       s("emailAddress"),
+		DisplayName: s("displayName"),
+		TimeZone:    s("timeZone"),
+	}
+}
+
+func object(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+	return v
+}
@@ -0,0 +1,181 @@
+package direct
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zogoapps/identity"
+)
+
+func directory(t *testing.T) *identity.Directory {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "idmap.csv")
+	mapping := "jira,cliq\nacc-bob,444\nbob@example.com,444\nmlee,555\nacc-ann,666\n"
+	if err := os.WriteFile(path, []byte(mapping), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := identity.Load(identity.Options{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func decode(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("%v in %s", err, body)
+	}
+	return payload
+}
+
+const (
+	bob   = `{"accountId": "acc-bob", "displayName": "Bob", "timeZone": "Europe/Berlin"}`
+	ann   = `{"accountId": "acc-ann", "displayName": "Ann"}`
+	mlee  = `{"name": "mlee", "displayName": "M Lee"}`
+	carol = `{"accountId": "acc-carol", "displayName": "Carol"}`
+)
+
+func issue(assignee string, reporter string) string {
+	return `{"key": "P-1", "fields": {"assignee": ` + assignee + `, "reporter": ` + reporter + `}}`
+}
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		optOut []string
+		want   string
+	}{
+		{
+			name: "created with an assignee",
+			body: `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `}`,
+			want: "444:assigned",
+		},
+		{
+			name: "created without an assignee",
+			body: `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue("null", ann) + `}`,
+		},
+		{
+			name: "assignee changed",
+			body: `{"webhookEvent": "jira:issue_updated", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `,
+				"changelog": {"items": [{"field": "assignee", "fieldId": "assignee", "to": "acc-bob"}]}}`,
+			want: "444:assigned",
+		},
+		{
+			name: "issue_assigned event",
+			body: `{"webhookEvent": "jira:issue_updated", "issue_event_type_name": "issue_assigned", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `}`,
+			want: "444:assigned",
+		},
+		{
+			name: "other change",
+			body: `{"webhookEvent": "jira:issue_updated", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `,
+				"changelog": {"items": [{"field": "status", "fieldId": "status"}]}}`,
+		},
+		{
+			name: "comment",
+			body: `{"webhookEvent": "comment_created", "comment": {"author": ` + carol + `}, "issue": ` + issue(bob, mlee) + `}`,
+			want: "444:commented 555:commented",
+		},
+		{
+			name: "comment without the reporter, as Jira Cloud sends it",
+			body: `{"webhookEvent": "comment_created", "comment": {"author": ` + carol + `}, "issue": {"key": "P-1", "fields": {"assignee": ` + bob + `}}}`,
+			want: "444:commented",
+		},
+		{
+			name: "comment by the assignee",
+			body: `{"webhookEvent": "comment_created", "comment": {"author": ` + bob + `}, "issue": ` + issue(bob, mlee) + `}`,
+			want: "555:commented",
+		},
+		{
+			name: "comment by an assignee who is also the reporter",
+			body: `{"webhookEvent": "comment_created", "comment": {"author": ` + carol + `}, "issue": ` + issue(bob, `{"emailAddress": "bob@example.com"}`) + `}`,
+			want: "444:commented",
+		},
+		{
+			name: "edited comment",
+			body: `{"webhookEvent": "comment_updated", "comment": {"author": ` + carol + `}, "issue": ` + issue(bob, mlee) + `}`,
+		},
+		{
+			name: "self-assignment",
+			body: `{"webhookEvent": "jira:issue_updated", "user": ` + bob + `, "issue": ` + issue(bob, ann) + `,
+				"changelog": {"items": [{"field": "assignee"}]}}`,
+		},
+		{
+			name: "self-assignment by another identity of the same Cliq user",
+			body: `{"webhookEvent": "jira:issue_updated", "user": {"emailAddress": "BOB@example.com"}, "issue": ` + issue(bob, ann) + `,
+				"changelog": {"items": [{"field": "assignee"}]}}`,
+		},
+		{
+			name: "unmapped assignee",
+			body: `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue(carol, ann) + `}`,
+		},
+		{
+			name:   "opted out by account ID",
+			body:   `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `}`,
+			optOut: []string{"ACC-BOB"},
+		},
+		{
+			name:   "opted out by Cliq ID",
+			body:   `{"webhookEvent": "comment_created", "comment": {"author": ` + carol + `}, "issue": ` + issue(bob, mlee) + `}`,
+			optOut: []string{" 555 "},
+			want:   "444:commented",
+		},
+	}
+	dir := directory(t)
+	for _, tt := range tests {
+		var got []string
+		for _, r := range Recipients(decode(t, tt.body), dir, NewOptOut(tt.optOut)) {
+			got = append(got, r.CliqID+":"+r.Reason)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: recipients = %q, want %q", tt.name, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestRecipientKeepsUser(t *testing.T) {
+	body := `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `}`
+	recipients := Recipients(decode(t, body), directory(t), nil)
+	if len(recipients) != 1 {
+		t.Fatalf("recipients = %+v, want one", recipients)
+	}
+	want := User{AccountID: "acc-bob", DisplayName: "Bob", TimeZone: "Europe/Berlin"}
+	if recipients[0].User != want {
+		t.Errorf("user = %+v, want %+v", recipients[0].User, want)
+	}
+}
+
+func TestRecipientsWithoutDirectory(t *testing.T) {
+	body := `{"webhookEvent": "jira:issue_created", "user": ` + ann + `, "issue": ` + issue(bob, ann) + `}`
+	if recipients := Recipients(decode(t, body), nil, nil); len(recipients) != 0 {
+		t.Errorf("recipients = %+v, want none without a directory", recipients)
+	}
+}
+
+func TestReasonOf(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"webhookEvent": "jira:issue_created"}`, ReasonAssigned},
+		{`{"webhookEvent": "comment_created"}`, ReasonCommented},
+		{`{"webhookEvent": "comment_updated"}`, ""},
+		{`{"webhookEvent": "comment_deleted"}`, ""},
+		{`{"webhookEvent": "jira:issue_deleted"}`, ""},
+		{`{"webhookEvent": "jira:issue_updated", "issue_event_type_name": "issue_assigned"}`, ReasonAssigned},
+		{`{"webhookEvent": "jira:issue_updated", "changelog": {"items": [{"field": "status"}, {"field": "Assignee"}]}}`, ReasonAssigned},
+		{`{"webhookEvent": "jira:issue_updated", "changelog": {"items": [{"field": "status"}]}}`, ""},
+		{`{"webhookEvent": "jira:issue_updated"}`, ""},
+		{`{}`, ""},
+	}
+	for _, tt := range tests {
+		if got := reasonOf(decode(t, tt.body)); got != tt.want {
+			t.Errorf("reasonOf(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+// Package dispatcher receives every Jira webhook on a single endpoint and
+// routes it to the handler registered for its event type.
+package dispatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+	"zogoapps/dedup"
+	"zogoapps/delivery"
+	"zogoapps/direct"
+	"zogoapps/identity"
+	"zogoapps/routing"
+	"zogoapps/rules"
+)
+
+// Renderer decodes the body of one kind of webhook event and builds the Cliq
+// message announcing it in the channel ch.
+type Renderer func(cfg *config.Config, ch config.Channel, body string) (cliq.Message, error)
+
+// ErrUnsupportedEvent is returned by Render for events without a renderer.
+var ErrUnsupportedEvent = errors.New("unsupported event")
+
+// webhookHeader holds the fields every Jira webhook body carries that tell us
+// which kind of event it is.
+type webhookHeader struct {
+	Timestamp          int64  `json:"timestamp"`
+	WebhookEvent       string `json:"webhookEvent"`
+	IssueEventTypeName string `json:"issue_event_type_name"`
+	Issue              struct {
+		ID     string `json:"id"`
+		Key    string `json:"key"`
+		Fields struct {
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+			Issuetype struct {
+				Name string `json:"name"`
+			} `json:"issuetype"`
+			Components []struct {
+				Name string `json:"name"`
+			} `json:"components"`
+			Labels []string `json:"labels"`
+		} `json:"fields"`
+	} `json:"issue"`
+}
+
+// routingIssue returns what the routes are matched against.
+func (h webhookHeader) routingIssue() routing.Issue {
+	fields := h.Issue.Fields
+	issue := routing.Issue{
+		Project: fields.Project.Key,
+		Type:    fields.Issuetype.Name,
+		Labels:  fields.Labels,
+	}
+	for _, c := range fields.Components {
+		issue.Components = append(issue.Components, c.Name)
+	}
+	return issue
+}
+
+var renderers = map[string]Renderer{}
+
+// The configuration loaded at cold start, or the reason it could not be loaded.
+var (
+	cfg    *config.Config
+	cfgErr error
+)
+
+// dedupStore remembers processed events. It is nil when deduplication is off.
+var dedupStore dedup.Store
+
+// Configure sets the configuration used for every request. It is called once
+// at cold start with the result of config.Load. When err is not nil every
+// request is answered with 503 and the reason is logged.
+func Configure(c *config.Config, err error) {
+	if err == nil {
+		dedupStore, err = dedup.Open(c.Dedup)
+	}
+	if err != nil {
+		log.Printf("Configuration error: %v", err)
+	}
+	cfg, cfgErr = c, err
+}
+
+// Register makes r the renderer for the given event name. The name is matched
+// against issue_event_type_name first (for example "issue_assigned") and then
+// against webhookEvent (for example "jira:issue_updated").
+func Register(event string, r Renderer) {
+	renderers[event] = r
+}
+
+// lookup returns the renderer for the event and the name it was found under.
+func lookup(header webhookHeader) (Renderer, string) {
+	if header.IssueEventTypeName != "" {
+		if r, ok := renderers[header.IssueEventTypeName]; ok {
+			return r, header.IssueEventTypeName
+		}
+	}
+	if r, ok := renderers[header.WebhookEvent]; ok {
+		return r, header.WebhookEvent
+	}
+	return nil, ""
+}
+
+// Render decodes a webhook body and builds the Cliq message for it in the
+// channel ch, exactly as LambdaHandler would before sending. It returns the
+// name the renderer was registered under, or ErrUnsupportedEvent.
+func Render(c *config.Config, ch config.Channel, body string) (string, cliq.Message, error) {
+	var header webhookHeader
+	if err := json.Unmarshal([]byte(body), &header); err != nil {
+		return "", cliq.Message{}, err
+	}
+	r, name := lookup(header)
+	if r == nil {
+		return "", cliq.Message{}, fmt.Errorf("%w %q", ErrUnsupportedEvent, header.WebhookEvent)
+	}
+	msg, err := r(c, ch, body)
+	return name, msg, err
+}
+
+// Plan is what the rules and routes decide for one event.
+type Plan struct {
+	// Action is rules.Send, rules.Drop or rules.Reroute.
+	Action string
+	// Rule names the rule that chose Action, or is "" for the default.
+	Rule string
+	// Route is where the event is sent: the channels of the chosen route
+	// whose filter the issue matches.
+	Route routing.Route
+	// Filtered are the channels of the chosen route whose filter the issue
+	// does not match.
+	Filtered []routing.Channel
+	// Direct are the direct messages to the people the event concerns.
+	Direct []DirectMessage
+	// Mentions are the Cliq mentions that mention rules add to the message.
+	Mentions []string
+}
+
+// DirectMessage is a direct message to one person.
+type DirectMessage struct {
+	Channel routing.Channel
+	// Reason is direct.ReasonAssigned or direct.ReasonCommented.
+	Reason string
+}
+
+// Decide evaluates the rules for a webhook body and picks its route, as
+// LambdaHandler does before rendering.
+func Decide(c *config.Config, body string) (Plan, error) {
+	var header webhookHeader
+	if err := json.Unmarshal([]byte(body), &header); err != nil {
+		return Plan{}, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		return Plan{}, err
+	}
+
+	d := c.Rules.Evaluate(payload)
+	plan := Plan{Action: d.Action, Rule: d.Rule}
+	seen := map[string]bool{}
+	for _, user := range d.Mentions {
+		var email string
+		if strings.Contains(user, "@") {
+			email = user
+		}
+		// Numbers the identity mapping does not know are taken to be Cliq user IDs
+		id := c.Directory.Lookup(user, user, email)
+		if id == "" && isDigits(user) {
+			id = user
+		}
+		if id == "" {
+			log.Printf("No Cliq user to mention for %q", user)
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			plan.Mentions = append(plan.Mentions, identity.Mention(id))
+		}
+	}
+
+	switch d.Action {
+	case rules.Drop:
+	case rules.Reroute:
+		if d.Route != "" {
+			route, ok := c.Routes.Named(d.Route)
+			if !ok {
+				return plan, fmt.Errorf("rule %q reroutes to unknown route %q", d.Rule, d.Route)
+			}
+			plan.Route = route
+			break
+		}
+		route, err := c.Routes.To(d.Rule, d.Channels)
+		if err != nil {
+			return plan, fmt.Errorf("rule %q reroutes to %w", d.Rule, err)
+		}
+		plan.Route = route
+	default:
+		plan.Route = c.Routes.Route(header.routingIssue())
+	}
+
+	issue, _ := payload["issue"].(map[string]interface{})
+	fields, _ := issue["fields"].(map[string]interface{})
+	plan.Route, plan.Filtered = plan.Route.Filter(fields)
+
+	if c.Direct.Endpoint != "" && plan.Action != rules.Drop {
+		for _, r := range direct.Recipients(payload, c.Directory, c.Direct.OptOut) {
+			plan.Direct = append(plan.Direct, DirectMessage{Channel: c.DirectChannel(r), Reason: r.Reason})
+		}
+	}
+	return plan, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Apply adds the plan's mentions to a rendered message.
+func (p Plan) Apply(msg cliq.Message) cliq.Message {
+	if len(p.Mentions) > 0 {
+		msg.Text += "\n" + strings.Join(p.Mentions, " ")
+	}
+	return msg
+}
+
+// LambdaHandler validates the request, reads the event type from the body and
+// passes the request on to the matching handler.
+func LambdaHandler(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Refuse to work with a broken configuration
+	// The reason is only logged, it can hold secrets from the environment
+	if cfgErr != nil || cfg == nil {
+		reason := "configuration has not been loaded"
+		if cfgErr != nil {
+			reason = cfgErr.Error()
+		}
+		log.Printf("Refusing request: %s", reason)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 503,
+			Body:       "Service unavailable: the function is misconfigured, see its logs.",
+		}, nil
+	}
+	// Check if the JSON data is empty
+	if event.Body == "" {
+		log.Println("Empty JSON data")
+		return events.APIGatewayProxyResponse{StatusCode: 400}, nil
+	}
+	// Function URLs base64-encode bodies they do not recognise as text
+	if event.IsBase64Encoded {
+		body, err := base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			log.Printf("Error decoding base64 body: %v", err)
+			return events.APIGatewayProxyResponse{StatusCode: 400}, nil
+		}
+		event.Body = string(body)
+		event.IsBase64Encoded = false
+	}
+
+	if resp, ok := authenticate(event); !ok {
+		return resp, nil
+	}
+
+	var header webhookHeader
+	if err := json.Unmarshal([]byte(event.Body), &header); err != nil {
+		log.Printf("Error unmarshaling JSON: %v", err)
+		return events.APIGatewayProxyResponse{StatusCode: 400}, nil
+	}
+
+	r, name := lookup(header)
+	if r == nil {
+		// Unknown events are acknowledged so Jira does not keep redelivering them
+		log.Printf("No handler for webhookEvent %q (issue_event_type_name %q)", header.WebhookEvent, header.IssueEventTypeName)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       fmt.Sprintf("Event %q is not supported and was ignored.", header.WebhookEvent),
+		}, nil
+	}
+
+	// Skip events that have already been processed. The claim only lasts as
+	// long as this attempt can, so that a timeout or crash does not make
+	// Jira's redelivery look like a duplicate.
+	key := dedupKey(event, header)
+	claimed := false
+	if dedupStore != nil && key != "" {
+		isNew, err := dedupStore.Claim(ctx, key, dedupLease(ctx))
+		if err != nil {
+			// Sending a duplicate is better than dropping the event
+			log.Printf("Error checking event %s for duplicates: %v", key, err)
+		} else if !isNew {
+			log.Printf("Skipping duplicate event %s", key)
+			return events.APIGatewayProxyResponse{
+				StatusCode: 200,
+				Body:       "Duplicate event was already processed and was ignored.",
+			}, nil
+		}
+		claimed = err == nil
+	}
+
+	log.Printf("Dispatching %s event", name)
+	resp, err := handle(ctx, r, name, key, header, event.Body)
+
+	// Let Jira's redelivery through if this attempt failed, and remember the
+	// event for the full TTL if it did not
+	if claimed && (err != nil || resp.StatusCode >= 500) {
+		if releaseErr := dedupStore.Release(ctx, key); releaseErr != nil {
+			log.Printf("Error releasing event %s: %v", key, releaseErr)
+		}
+	} else if claimed {
+		if extendErr := dedupStore.Extend(ctx, key, cfg.Dedup.TTL); extendErr != nil {
+			log.Printf("Error remembering event %s: %v", key, extendErr)
+		}
+	}
+	return resp, err
+}
+
+// dedupLeaseMargin is how long a claim outlives the deadline of the request
+// that took it.
+const dedupLeaseMargin = 10 * time.Second
+
+// dedupLease returns how long to claim an event while it is processed:
+// DEDUP_LEASE, or until shortly after the request's deadline if that is
+// sooner.
+func dedupLease(ctx context.Context) time.Duration {
+	lease := cfg.Dedup.Lease
+	if deadline, ok := ctx.Deadline(); ok {
+		if untilDeadline := time.Until(deadline) + dedupLeaseMargin; untilDeadline < lease {
+			lease = untilDeadline
+		}
+	}
+	return lease
+}
+
+// handle evaluates the rules, renders the message for each channel of the
+// event's route and delivers them to Cliq. key is the event's dedup key, or
+// "" when it has none.
+func handle(ctx context.Context, r Renderer, name string, key string, header webhookHeader, body string) (events.APIGatewayProxyResponse, error) {
+	plan, err := Decide(cfg, body)
+	if err != nil {
+		log.Printf("Error evaluating rules for %s event: %v", name, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500}, err
+	}
+	if plan.Action == rules.Drop {
+		decidedBy := "the default action of the rules"
+		if plan.Rule != "" {
+			decidedBy = fmt.Sprintf("rule %q", plan.Rule)
+		}
+		log.Printf("Dropping %s event for %s by %s", name, header.Issue.Key, decidedBy)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       fmt.Sprintf("Event %s for %s was dropped by %s.", name, header.Issue.Key, decidedBy),
+		}, nil
+	}
+	if plan.Rule != "" {
+		log.Printf("Rule %q chose %s for %s event %s", plan.Rule, plan.Action, name, header.Issue.Key)
+	}
+	route := plan.Route
+	for _, ch := range plan.Filtered {
+		log.Printf("Skipping channel %q, %s does not match its filter %q", ch.Name, header.Issue.Key, ch.Filter)
+	}
+	if len(route.Channels)+len(plan.Direct) == 0 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       fmt.Sprintf("No channel of route %q accepts %s, nothing was sent.", route.Name, header.Issue.Key),
+		}, nil
+	}
+	log.Printf("Routing %s to route %q: %s", header.Issue.Key, route.Name, strings.Join(route.ChannelNames, ", "))
+
+	// Render every message before sending any, so that a broken template
+	// does not leave some channels notified and others not
+	var targets []delivery.Target
+	for _, ch := range route.Channels {
+		message, err := r(cfg, ch, body)
+		if err != nil {
+			log.Printf("Error rendering %s event for channel %q: %v", name, ch.Name, err)
+			return events.APIGatewayProxyResponse{StatusCode: 500}, err
+		}
+		targets = append(targets, delivery.Target{Channel: ch, Message: plan.Apply(message)})
+	}
+	// Direct messages go to one person, so rule mentions are left out
+	reasons := map[string]string{}
+	for _, dm := range plan.Direct {
+		message, err := r(cfg, dm.Channel, body)
+		if err != nil {
+			log.Printf("Error rendering %s event for %s: %v", name, dm.Channel.Name, err)
+			return events.APIGatewayProxyResponse{StatusCode: 500}, err
+		}
+		targets = append(targets, delivery.Target{Channel: dm.Channel, Message: message})
+		reasons[dm.Channel.Endpoint] = dm.Reason
+	}
+
+	// Send the messages to Zoho Cliq, except to the destinations an earlier
+	// attempt at this event already reached
+	total := len(targets)
+	targets, done := claimDestinations(ctx, key, targets)
+	results := delivery.FanOut(ctx, cfg, header.WebhookEvent, body, targets)
+	settleDestinations(ctx, key, results)
+	var sendErr error
+	var lines []string
+	sent := len(done)
+	for _, dest := range done {
+		log.Printf("Skipping %s, an earlier delivery of %s reached it", dest, header.Issue.Key)
+		lines = append(lines, fmt.Sprintf("%s: already sent", dest))
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			log.Printf("Error sending message to Zoho Cliq channel %q after %s: %v", res.Channel.Name, res.Duration.Round(time.Millisecond), res.Err)
+			lines = append(lines, fmt.Sprintf("%s: failed after %s: %v", res.Channel.Name, res.Duration.Round(time.Millisecond), res.Err))
+			// A temporary failure decides the response, so that Jira redelivers
+			if sendErr == nil || cliq.IsRetryable(res.Err) {
+				sendErr = res.Err
+			}
+			continue
+		}
+		sent++
+		if reason, ok := reasons[res.Channel.Endpoint]; ok {
+			log.Printf("Sent a direct message about %s to %s (%s)", header.Issue.Key, res.Channel.Name, reason)
+		}
+		lines = append(lines, fmt.Sprintf("%s: sent (%d) in %s", res.Channel.Name, res.StatusCode, res.Duration.Round(time.Millisecond)))
+	}
+	summary := fmt.Sprintf("Sent %s notification for %s via route %q to %d of %d destinations.\n%s",
+		name, header.Issue.Key, route.Name, sent, total, strings.Join(lines, "\n"))
+	if sendErr != nil {
+		resp := deliveryFailed(sendErr)
+		resp.Body = summary + "\n" + resp.Body
+		return resp, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       summary,
+	}, nil
+}
+
+// authenticate checks the webhook signature and the lamda-auth query
+// parameter. When it returns false the response should be sent back as is.
+func authenticate(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, bool) {
+	if cfg.SignatureMode != config.SignatureOff {
+		signature := headerValue(event.Headers, signatureHeader)
+		if signature != "" {
+			if !verifySignature([]byte(event.Body), signature, cfg.WebhookSecret) {
+				log.Println("Rejected request with an invalid webhook signature")
+				return events.APIGatewayProxyResponse{
+					StatusCode: 401,
+					Body:       "The webhook signature is invalid.",
+				}, false
+			}
+			log.Println("Authenticated with webhook signature")
+			return events.APIGatewayProxyResponse{}, true
+		}
+		if cfg.SignatureMode == config.SignatureRequired {
+			log.Println("Rejected request without a webhook signature")
+			return events.APIGatewayProxyResponse{
+				StatusCode: 401,
+				Body:       "The webhook signature is missing.",
+			}, false
+		}
+	}
+
+	// Check if the query parameter matches one of the accepted credentials
+	customParam, paramExists := event.QueryStringParameters["lamda-auth"]
+	cred, ok, expired := config.MatchCredential(cfg.Credentials, customParam, time.Now())
+	if expired {
+		log.Printf("Rejected expired credential %q", cred.ID)
+	}
+	if !paramExists || !ok {
+		// Return a response indicating that the parameter is missing or has an invalid value
+		return events.APIGatewayProxyResponse{
+			StatusCode: 400, // Bad Request
+			Body:       "The 'Authenticaion' query parameter is missing or has an invalid value.",
+		}, false
+	}
+	log.Printf("Authenticated with credential %q", cred.ID)
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// deliveryFailed returns the response for a webhook whose message could not
+// be delivered to Cliq. Temporary failures answer 503 so that Jira redelivers
+// the webhook later; anything else answers 424, which Jira does not retry.
+func deliveryFailed(err error) events.APIGatewayProxyResponse {
+	if cliq.IsRetryable(err) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 503,
+			Body:       "Zoho Cliq is temporarily unavailable: " + err.Error(),
+		}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: 424,
+		Body:       "Zoho Cliq rejected the message: " + err.Error(),
+	}
+}
@@ -0,0 +1,84 @@
+package dispatcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"zogoapps/config"
+	"zogoapps/delivery"
+)
+
+// webhookIdentifierHeader is the header Jira Cloud uses to send an ID that
+// stays the same across retries of one webhook delivery.
+const webhookIdentifierHeader = "X-Atlassian-Webhook-Identifier"
+
+// dedupKey identifies the event for deduplication. It prefers Jira's webhook
+// identifier and otherwise combines the timestamp, issue ID and event type.
+// It returns "" when the event carries too little to identify it safely.
+func dedupKey(event events.APIGatewayProxyRequest, header webhookHeader) string {
+	if id := headerValue(event.Headers, webhookIdentifierHeader); id != "" {
+		return "webhook:" + id
+	}
+	if header.Timestamp == 0 {
+		return ""
+	}
+	return fmt.Sprintf("event:%d:%s:%s:%s", header.Timestamp, header.Issue.ID, header.WebhookEvent, header.IssueEventTypeName)
+}
+
+// destinationKey identifies the delivery of the event with the given key to
+// one destination. The endpoint is hashed so that tokens in its URL are not
+// stored, and so that two people with the same display name stay apart.
+func destinationKey(key string, ch config.Channel) string {
+	sum := sha256.Sum256([]byte(ch.Endpoint))
+	return key + "/" + ch.Name + "/" + hex.EncodeToString(sum[:8])
+}
+
+// claimDestinations claims the destination of each target for the event
+// with the given key. It returns the targets that still need their message
+// and the names of the destinations an earlier attempt already reached.
+func claimDestinations(ctx context.Context, key string, targets []delivery.Target) ([]delivery.Target, []string) {
+	if dedupStore == nil || key == "" {
+		return targets, nil
+	}
+	var pending []delivery.Target
+	var done []string
+	for _, t := range targets {
+		isNew, err := dedupStore.Claim(ctx, destinationKey(key, t.Channel), dedupLease(ctx))
+		if err != nil {
+			// Sending a duplicate is better than dropping the message
+			log.Printf("Error checking delivery of %s to %s: %v", key, t.Channel.Name, err)
+		}
+		if err == nil && !isNew {
+			done = append(done, t.Channel.Name)
+			continue
+		}
+		pending = append(pending, t)
+	}
+	return pending, done
+}
+
+// settleDestinations releases the destinations the message could not be
+// delivered to, so that Jira's redelivery tries them again, and remembers the
+// others for the full TTL.
+func settleDestinations(ctx context.Context, key string, results []delivery.Result) {
+	if dedupStore == nil || key == "" {
+		return
+	}
+	for _, res := range results {
+		destKey := destinationKey(key, res.Channel)
+		if res.Err != nil {
+			if err := dedupStore.Release(ctx, destKey); err != nil {
+				log.Printf("Error releasing delivery of %s to %s: %v", key, res.Channel.Name, err)
+			}
+			continue
+		}
+		if err := dedupStore.Extend(ctx, destKey, cfg.Dedup.TTL); err != nil {
+			log.Printf("Error remembering delivery of %s to %s: %v", key, res.Channel.Name, err)
+		}
+	}
+}
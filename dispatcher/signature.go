@@ -0,0 +1,41 @@
+package dispatcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// signatureHeader is the header Jira uses to send the HMAC of the body.
+const signatureHeader = "X-Hub-Signature"
+
+// verifySignature reports whether signature, in the "sha256=<hex>" form Jira
+// sends, is the HMAC-SHA256 of body keyed with secret.
+func verifySignature(body []byte, signature string, secret string) bool {
+	sum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// headerValue returns the value of the named header. Function URLs lower-case
+// header names, so the lookup ignores case.
+func headerValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
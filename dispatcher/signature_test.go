@@ -0,0 +1,113 @@
+package dispatcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"zogoapps/config"
+)
+
+const (
+	testSecret = "webhook-secret"
+	testCred   = "lambda-cred"
+	testBody   = `{"webhookEvent":"jira:issue_created"}`
+)
+
+func sign(body string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	valid := sign(testBody, testSecret)
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		want      bool
+	}{
+		{"valid", testBody, valid, true},
+		{"tampered body", testBody + " ", valid, false},
+		{"other secret", testBody, sign(testBody, "other"), false},
+		{"sha1 prefix", testBody, "sha1=" + valid[len("sha256="):], false},
+		{"no prefix", testBody, valid[len("sha256="):], false},
+		{"upper-case prefix", testBody, "SHA256=" + valid[len("sha256="):], false},
+		{"not hex", testBody, "sha256=zz", false},
+		{"truncated", testBody, valid[:len(valid)-2], false},
+		{"empty", testBody, "", false},
+	}
+	for _, tt := range tests {
+		if got := verifySignature([]byte(tt.body), tt.signature, testSecret); got != tt.want {
+			t.Errorf("%s: verifySignature = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	valid := sign(testBody, testSecret)
+	tampered := sign(testBody+"tampered", testSecret)
+	wrongPrefix := "sha1=" + valid[len("sha256="):]
+
+	// status is the expected response status, or 0 when the request passes
+	tests := []struct {
+		mode      string
+		name      string
+		signature string
+		cred      string
+		status    int
+	}{
+		{config.SignatureOff, "valid signature without credential", valid, "", 400},
+		{config.SignatureOff, "valid signature", valid, testCred, 0},
+		{config.SignatureOff, "tampered signature", tampered, testCred, 0},
+		{config.SignatureOff, "wrong prefix", wrongPrefix, testCred, 0},
+		{config.SignatureOff, "missing signature", "", testCred, 0},
+		{config.SignatureOff, "missing signature and wrong credential", "", "wrong", 400},
+
+		{config.SignatureOptional, "valid signature", valid, "", 0},
+		{config.SignatureOptional, "tampered signature", tampered, testCred, 401},
+		{config.SignatureOptional, "wrong prefix", wrongPrefix, testCred, 401},
+		{config.SignatureOptional, "missing signature", "", testCred, 0},
+		{config.SignatureOptional, "missing signature and credential", "", "", 400},
+
+		{config.SignatureRequired, "valid signature", valid, "", 0},
+		{config.SignatureRequired, "tampered signature", tampered, testCred, 401},
+		{config.SignatureRequired, "wrong prefix", wrongPrefix, testCred, 401},
+		{config.SignatureRequired, "missing signature", "", testCred, 401},
+	}
+
+	saved := cfg
+	defer func() { cfg = saved }()
+	for _, tt := range tests {
+		cfg = &config.Config{
+			SignatureMode: tt.mode,
+			WebhookSecret: testSecret,
+			Credentials:   []config.Credential{{ID: "default", Secret: testCred}},
+		}
+		event := events.APIGatewayProxyRequest{
+			Body:    testBody,
+			Headers: map[string]string{},
+		}
+		if tt.signature != "" {
+			// Function URLs lower-case header names
+			event.Headers["x-hub-signature"] = tt.signature
+		}
+		if tt.cred != "" {
+			event.QueryStringParameters = map[string]string{"lamda-auth": tt.cred}
+		}
+
+		resp, ok := authenticate(event)
+		switch {
+		case tt.status == 0 && !ok:
+			t.Errorf("%s, %s: rejected with %d %q, want accepted", tt.mode, tt.name, resp.StatusCode, resp.Body)
+		case tt.status != 0 && ok:
+			t.Errorf("%s, %s: accepted, want %d", tt.mode, tt.name, tt.status)
+		case tt.status != 0 && resp.StatusCode != tt.status:
+			t.Errorf("%s, %s: status %d, want %d", tt.mode, tt.name, resp.StatusCode, tt.status)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package i18n holds the catalogs of translated message strings. Catalogs
+// for English, German and Japanese are embedded, and a directory of JSON
+// files can add locales or change strings.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used for strings a catalog does not translate.
+const DefaultLocale = "en"
+
+//go:embed catalogs/*.json
+var embedded embed.FS
+
+// Default holds the embedded catalogs.
+var Default = mustLoad()
+
+// Translator returns the string for a catalog key in one locale. When
+// args are given the string is a fmt format, so a translation can reorder
+// them with %[1]s.
+type Translator func(key string, args ...interface{}) string
+
+// Catalogs maps each locale to its strings, keyed by event and label, for
+// example "issue_updated.heading" or "label.assignee".
+type Catalogs struct {
+	messages map[string]map[string]string
+}
+
+// Load returns the embedded catalogs with the *.json files in dir laid over
+// them. Each file is named after its locale, such as fr.json or pt-br.json,
+// and holds an object of keys and strings. A key missing from a file keeps
+// its embedded string.
+func Load(dir string) (*Catalogs, error) {
+	c := &Catalogs{messages: map[string]map[string]string{}}
+	files, err := embedded.ReadDir("catalogs")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		data, err := embedded.ReadFile(path.Join("catalogs", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := c.add(f.Name(), data); err != nil {
+			return nil, fmt.Errorf("embedded catalog %s: %w", f.Name(), err)
+		}
+	}
+	if dir == "" {
+		return c, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.add(filepath.Base(p), data); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", p, err)
+		}
+	}
+	return c, nil
+}
+
+func mustLoad() *Catalogs {
+	c, err := Load("")
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Catalogs) add(name string, data []byte) error {
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return err
+	}
+	locale := normalize(strings.TrimSuffix(name, ".json"))
+	if c.messages[locale] == nil {
+		c.messages[locale] = map[string]string{}
+	}
+	for k, v := range messages {
+		c.messages[locale][k] = v
+	}
+	return nil
+}
+
+// Locales lists the locales that have a catalog.
+func (c *Catalogs) Locales() []string {
+	var locales []string
+	for l := range c.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Has reports whether there is a catalog for locale or for its language,
+// for example for "de" when locale is "de-AT".
+func (c *Catalogs) Has(locale string) bool {
+	for _, l := range fallbacks(locale) {
+		if _, ok := c.messages[l]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Translator returns the translator for locale. A key is looked up in the
+// locale's catalog, then its language's, then DefaultLocale's. A key that
+// no catalog has is returned as it is. A nil *Catalogs uses Default.
+func (c *Catalogs) Translator(locale string) Translator {
+	if c == nil {
+		c = Default
+	}
+	chain := append(fallbacks(locale), DefaultLocale)
+	return func(key string, args ...interface{}) string {
+		msg := key
+		for _, l := range chain {
+			if s, ok := c.messages[l][key]; ok {
+				msg = s
+				break
+			}
+		}
+		if len(args) > 0 {
+			return fmt.Sprintf(msg, args...)
+		}
+		return msg
+	}
+}
+
+// fallbacks returns locale and then its language, normalized.
+func fallbacks(locale string) []string {
+	locale = normalize(locale)
+	if locale == "" {
+		return nil
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		return []string{locale, lang}
+	}
+	return []string{locale}
+}
+
+// normalize lowercases locale and writes pt_BR as pt-br.
+func normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,137 @@
+// Package identity maps Jira users to Zoho Cliq users so that notifications
+// can mention the people they are about. The mapping comes from a JSON or
+// CSV file, from matching emails against the Cliq users API, or both.
+package identity
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options says where the mapping comes from.
+type Options struct {
+	// File maps Jira account IDs, usernames or emails to Cliq user IDs. A
+	// .csv file has one "jira,cliq" pair per row; any other file is a JSON
+	// object.
+	File string
+	// UsersAPI is the URL of the Cliq users API. When set, Jira users are
+	// matched to Cliq users by email. Token is the OAuth token it is called
+	// with.
+	UsersAPI string
+	Token    string
+}
+
+// Directory finds the Cliq user of a Jira user.
+type Directory struct {
+	static map[string]string
+	users  *UsersAPI
+}
+
+// Load reads the mapping file, if any, and returns the directory. It
+// returns nil when neither a file nor the users API is configured.
+func Load(opts Options) (*Directory, error) {
+	if opts.File == "" && opts.UsersAPI == "" {
+		return nil, nil
+	}
+	d := &Directory{static: map[string]string{}}
+	if opts.File != "" {
+		m, err := readFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		for jira, cliq := range m {
+			d.static[normalize(jira)] = cliq
+		}
+	}
+	if opts.UsersAPI != "" {
+		d.users = NewUsersAPI(opts.UsersAPI, opts.Token)
+	}
+	return d, nil
+}
+
+// Lookup returns the Cliq user ID of the Jira user with the given account
+// ID, username or email, whichever are known, or "" when the user is not
+// mapped. The file is consulted first, then the users API by email.
+func (d *Directory) Lookup(accountID string, username string, email string) string {
+	if d == nil {
+		return ""
+	}
+	for _, key := range []string{accountID, username, email} {
+		if key == "" {
+			continue
+		}
+		if id, ok := d.static[normalize(key)]; ok {
+			return id
+		}
+	}
+	if d.users != nil && email != "" {
+		return d.users.Lookup(email)
+	}
+	return ""
+}
+
+// Mention returns the Cliq markup that mentions a user.
+func Mention(cliqUserID string) string {
+	return "{@" + cliqUserID + "}"
+}
+
+// normalize makes emails match regardless of case. Account IDs and
+// usernames never contain @, so they are left alone.
+func normalize(key string) string {
+	key = strings.TrimSpace(key)
+	if strings.Contains(key, "@") {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("identity map: %w", err)
+	}
+	defer f.Close()
+
+	var m map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		m, err = readCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("identity map %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// readCSV reads "jira,cliq" rows. A header row naming those columns and
+// lines starting with # are skipped.
+func readCSV(r io.Reader) (map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	m := map[string]string{}
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && strings.EqualFold(record[0], "jira") && strings.EqualFold(record[1], "cliq") {
+			continue
+		}
+		if record[0] == "" || record[1] == "" {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("line %d: both columns must be set", line)
+		}
+		m[record[0]] = record[1]
+	}
+}
@@ -0,0 +1,156 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the user list is kept before it is fetched again, and how long
+// to wait after a failed fetch.
+const (
+	usersRefresh    = time.Hour
+	usersRetryDelay = time.Minute
+	usersTimeout    = 10 * time.Second
+)
+
+// UsersAPI matches emails to Cliq users with the Cliq users API. The whole
+// user list is fetched on the first lookup and cached.
+type UsersAPI struct {
+	// URL is the users API, for example https://cliq.zoho.com/api/v2/users.
+	URL string
+	// Token is the Zoho OAuth token the API is called with.
+	Token string
+	// HTTPClient is used for requests. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	byEmail map[string]string
+	next    time.Time
+	// fetching is closed when the fetch in progress ends. It is nil when no
+	// fetch is in progress.
+	fetching chan struct{}
+}
+
+// NewUsersAPI returns a UsersAPI for the given URL and OAuth token.
+func NewUsersAPI(apiURL string, token string) *UsersAPI {
+	return &UsersAPI{URL: apiURL, Token: token}
+}
+
+// usersPage is one page of the users API response.
+type usersPage struct {
+	Data []struct {
+		ID      string `json:"id"`
+		EmailID string `json:"email_id"`
+	} `json:"data"`
+	HasMore   bool   `json:"has_more"`
+	NextToken string `json:"next_token"`
+}
+
+// Lookup returns the ID of the Cliq user with the given email, or "". When
+// the user list cannot be fetched the last list fetched is used.
+//
+// The list is fetched by one caller at a time, without holding the lock.
+// Other callers go on with the last list while it is refreshed, and only
+// wait when there is no list yet.
+func (u *UsersAPI) Lookup(email string) string {
+	u.mu.Lock()
+	fetching := u.fetching
+	switch {
+	case fetching == nil && time.Now().After(u.next):
+		u.fetching = make(chan struct{})
+		u.mu.Unlock()
+		u.refresh()
+		u.mu.Lock()
+	case fetching != nil && u.byEmail == nil:
+		u.mu.Unlock()
+		<-fetching
+		u.mu.Lock()
+	}
+	id := u.byEmail[strings.ToLower(email)]
+	u.mu.Unlock()
+	return id
+}
+
+// refresh fetches the user list and ends the fetch in progress.
+func (u *UsersAPI) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), usersTimeout)
+	users, err := u.fetch(ctx)
+	cancel()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil {
+		log.Printf("Fetching Cliq users: %v", err)
+		u.next = time.Now().Add(usersRetryDelay)
+	} else {
+		u.byEmail = users
+		u.next = time.Now().Add(usersRefresh)
+	}
+	close(u.fetching)
+	u.fetching = nil
+}
+
+// fetch reads every page of the user list.
+func (u *UsersAPI) fetch(ctx context.Context) (map[string]string, error) {
+	users := map[string]string{}
+	token := ""
+	for {
+		page, err := u.page(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range page.Data {
+			if user.EmailID != "" {
+				users[strings.ToLower(user.EmailID)] = user.ID
+			}
+		}
+		if !page.HasMore || page.NextToken == "" {
+			return users, nil
+		}
+		token = page.NextToken
+	}
+}
+
+func (u *UsersAPI) page(ctx context.Context, nextToken string) (*usersPage, error) {
+	endpoint, err := url.Parse(u.URL)
+	if err != nil {
+		return nil, err
+	}
+	if nextToken != "" {
+		q := endpoint.Query()
+		q.Set("next_token", nextToken)
+		endpoint.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Zoho-oauthtoken "+u.Token)
+
+	client := u.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var page usersPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decoding users: %w", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,83 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// usersServer serves a two-page user list, each page after delay.
+func usersServer(t *testing.T, requests *int32, delay *int64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		time.Sleep(time.Duration(atomic.LoadInt64(delay)))
+		if r.URL.Query().Get("next_token") == "" {
+			w.Write([]byte(`{"data": [{"id": "111", "email_id": "Ann@example.com"}], "has_more": true, "next_token": "p2"}`))
+			return
+		}
+		w.Write([]byte(`{"data": [{"id": "222", "email_id": "bob@example.com"}], "has_more": false}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUsersAPILookup(t *testing.T) {
+	var requests int32
+	delay := int64(100 * time.Millisecond)
+	api := NewUsersAPI(usersServer(t, &requests, &delay).URL, "token")
+
+	// Concurrent first lookups share one fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id := api.Lookup("ann@example.com"); id != "111" {
+				t.Errorf("Lookup(ann) = %q, want 111", id)
+			}
+		}()
+	}
+	wg.Wait()
+	if id := api.Lookup("BOB@example.com"); id != "222" {
+		t.Errorf("Lookup(bob) = %q, want 222", id)
+	}
+	if id := api.Lookup("nobody@example.com"); id != "" {
+		t.Errorf("Lookup(nobody) = %q, want none", id)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want the 2 pages once", requests)
+	}
+}
+
+func TestUsersAPIRefreshDoesNotBlock(t *testing.T) {
+	var requests int32
+	delay := int64(0)
+	api := NewUsersAPI(usersServer(t, &requests, &delay).URL, "token")
+	api.Lookup("ann@example.com")
+
+	// Make the list stale and the next fetch slow
+	api.mu.Lock()
+	api.next = time.Now().Add(-time.Second)
+	api.mu.Unlock()
+	atomic.StoreInt64(&delay, int64(time.Second))
+
+	refreshed := make(chan struct{})
+	go func() {
+		api.Lookup("ann@example.com")
+		close(refreshed)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if id := api.Lookup("bob@example.com"); id != "222" {
+		t.Errorf("Lookup(bob) during a refresh = %q, want 222 from the last list", id)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Lookup during a refresh took %s, want it not to wait", elapsed)
+	}
+	<-refreshed
+}
@@ -1,8 +0,0 @@
-module zogoapps
-
-go 1.20
-
-require (
-	github.com/aws/aws-lambda-go v1.41.0 // indirect
-	github.com/eawsy/aws-lambda-go-event v0.0.0-20171129201522-e888a5ec6428 // indirect
-)
@@ -1,16 +1,12 @@
-package main
+package updated
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
 
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-lambda-go/lambda"
+	"zogoapps/changelog"
+	"zogoapps/cliq"
+	"zogoapps/config"
+	"zogoapps/templates"
 )
 
 type StatusChange struct {
@@ -170,10 +166,12 @@ type StatusChange struct {
 					One6X16   string `json:"16x16"`
 					Three2X32 string `json:"32x32"`
 				} `json:"avatarUrls"`
-				DisplayName string `json:"displayName"`
-				Active      bool   `json:"active"`
-				TimeZone    string `json:"timeZone"`
-				AccountType string `json:"accountType"`
+				DisplayName  string `json:"displayName"`
+				Name         string `json:"name"`
+				EmailAddress string `json:"emailAddress"`
+				Active       bool   `json:"active"`
+				TimeZone     string `json:"timeZone"`
+				AccountType  string `json:"accountType"`
 			} `json:"reporter"`
 			Aggregateprogress struct {
 				Progress int `json:"progress"`
@@ -205,133 +203,63 @@ type StatusChange struct {
 			From       string `json:"from"`
 			FromString string `json:"fromString"`
 			To         string `json:"to"`
+			ToString   string `json:"toString"`
 		} `json:"items"`
 	} `json:"changelog"`
 }
 
-func LambdaHandler(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Check if the JSON data is empty
-	if event.Body == "" {
-		log.Println("Empty JSON data")
-		return events.APIGatewayProxyResponse{StatusCode: 400}, nil
-	}
-	// Check if the query parameter is eqal to the env
-	// Get lamda cred from env
-	lambdaCred := os.Getenv("LAMBDA_CRED")
-	if lambdaCred == "" {
-		panic("jira_URL environment variable is not set")
-	}
-	customParam, paramExists := event.QueryStringParameters["lamda-auth"]
-	if !paramExists || customParam != lambdaCred {
-		// Return a response indicating that the parameter is missing or has an invalid value
-		return events.APIGatewayProxyResponse{
-			StatusCode: 400, // Bad Request
-			Body:       "The 'Authenticaion' query parameter is missing or has an invalid value.",
-		}, nil
-	}
-
+// Render decodes an issue updated webhook body and builds the Cliq message for it.
+func Render(cfg *config.Config, ch config.Channel, body string) (cliq.Message, error) {
 	var eventData StatusChange
 
 	// Unmarshal the JSON data
-	if err := json.Unmarshal([]byte(event.Body), &eventData); err != nil {
-		log.Printf("Error unmarshaling JSON: %v", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500}, err
+	if err := json.Unmarshal([]byte(body), &eventData); err != nil {
+		return cliq.Message{}, err
 	}
 
-	// Extract the required fields
-	issueKey := eventData.Issue.Key
-	issueSummary := eventData.Issue.Fields.Summary
+	timeZone := templates.TimeZone(ch.TimeZone, eventData.User.TimeZone)
 
-	// Extract Assignee DisplayName using a type assertion
-	var assigneeDisplayName string
-	if assignee, ok := eventData.Issue.Fields.Assignee.(map[string]interface{}); ok {
-		if displayName, ok := assignee["displayName"].(string); ok {
-			assigneeDisplayName = displayName
-		}
+	// Render the text and card from the template
+	issueLink := cfg.JiraURL + "/browse/" + eventData.Issue.Key
+	data := templates.Data{
+		Event:     eventData.WebhookEvent,
+		Payload:   &eventData,
+		Issue:     eventData.Issue,
+		Fields:    eventData.Issue.Fields,
+		User:      eventData.User,
+		Changelog: eventData.Changelog,
+		Locale:    ch.Locale,
+		TimeZone:  timeZone,
+		JiraURL:   cfg.JiraURL,
+		IssueURL:  issueLink,
 	}
-	// Extract the Reporter name
-	reporterDisplayName := eventData.Issue.Fields.Reporter.DisplayName
-
-	// Extract the project name
-	projectName := eventData.Issue.Fields.Project.Name
-
-	// Construct the output
-	output := fmt.Sprintf("Issue Key: %s\nSummary: %s\nAssignee Display Name: %s", issueKey, issueSummary, assigneeDisplayName, reporterDisplayName, projectName)
-
-	// Log the output (you can remove this in production)
-	SendZohoMessge(issueKey, issueSummary, assigneeDisplayName, reporterDisplayName, projectName)
-
-	// Return a successful response with the extracted data
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Body:       output,
-	}, nil
-}
+	json.Unmarshal([]byte(body), &data.Raw)
+	data.Slides = cfg.Slides[templates.IssueUpdated]
 
-func main() {
-	lambda.Start(LambdaHandler)
-}
-
-func SendZohoMessge(issueKey string, issueSummary string, assigneeDisplayName string, reporterDisplayName string, projectName string) {
-	// URL for sending messages to the channel
-	apiToken := os.Getenv("ZOHO_CLIQ_API_TOKEN")
-	if apiToken == "" {
-		panic("ZOHO_CLIQ_API_TOKEN environment variable is not set")
-	}
-
-	// Jira Url
-	jiraUrl := os.Getenv("JIRA_URL")
-	if apiToken == "" {
-		panic("jira_URL environment variable is not set")
+	// List every changed field instead of a generic "updated" line
+	var items []changelog.Item
+	for _, item := range eventData.Changelog.Items {
+		items = append(items, changelog.Item{
+			Field:      item.Field,
+			FieldType:  item.Fieldtype,
+			FieldID:    item.FieldID,
+			From:       item.From,
+			FromString: item.FromString,
+			To:         item.To,
+			ToString:   item.ToString,
+		})
 	}
-	channelId := os.Getenv("CHANNEL_ENDPOINT")
-	if apiToken == "" {
-		panic("jira_URL environment variable is not set")
-	}
-	url := channelId + "?zapikey=" + apiToken
-	issueLink := jiraUrl + "/browse/" + issueKey
-	message := map[string]interface{}{
-		"text": "Jira Updates \n" + "The Issue " + issueKey + " has been Updated in Jira" + "\n Project Name:   " + projectName + "\n Issue ID:   " + issueKey + "\n Issue Summary:   " + issueSummary + "\n Assignee:   " + assigneeDisplayName + "\n Reporter:  " + reporterDisplayName + "\n Issue Status changed",
-		"card": map[string]interface{}{
-			"theme":     "prompt",
-			"thumbnail": "https://www.zoho.com/cliq/help/restapi/images/announce_icon.png",
-		},
-		"buttons": []map[string]interface{}{
-			{
-				"label": "View Issue",
-				"type":  "+",
-				"action": map[string]interface{}{
-					"type": "open.url",
-					"data": map[string]interface{}{
-						"web": issueLink,
-					},
-				},
-			},
-		},
-	}
-
-	// Convert the message to JSON
-	payload, _ := json.Marshal(message)
-
-	// Create an HTTP client
-	client := &http.Client{}
-
-	// Create a request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	if err != nil {
-		panic(err)
+	var assigneeDisplayName string
+	if assignee, ok := eventData.Issue.Fields.Assignee.(map[string]interface{}); ok {
+		assigneeDisplayName, _ = assignee["displayName"].(string)
 	}
+	data.Changes = changelog.Changes(items, changelog.Options{Assignee: assigneeDisplayName})
 
-	// Set the API token as an HTTP header
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	resp, err := client.Do(req)
+	message, err := cfg.Templates.Render(templates.IssueUpdated, data)
 	if err != nil {
-		panic(err)
+		return cliq.Message{}, err
 	}
-	defer resp.Body.Close()
-
-	// Print the response status code and body
-	println("Response Status Code:", resp.Status)
+	tr := cfg.Templates.Translator(ch.Locale)
+	message.Buttons = []cliq.Button{cliq.OpenURLButton(tr("button.view_issue"), issueLink)}
+	return message, nil
 }
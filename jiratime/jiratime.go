@@ -0,0 +1,51 @@
+// Package jiratime parses the timestamps and dates in Jira webhook bodies.
+package jiratime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layouts of the values Jira sends. Timestamps such as "created" carry
+// milliseconds and a zone offset; dates such as "duedate" carry neither.
+const (
+	Layout     = "2006-01-02T15:04:05.000-0700"
+	DateLayout = "2006-01-02"
+)
+
+// layouts are tried in order. Go accepts fractional seconds after the
+// seconds field whether or not the layout has them, so these also cover
+// values with and without milliseconds.
+var layouts = []string{
+	"2006-01-02T15:04:05Z0700",
+	time.RFC3339,
+	"2006-01-02T15:04Z0700",
+	"2006-01-02 15:04:05Z0700",
+}
+
+// Parse reads a Jira timestamp or date. dateOnly is true for a date, which
+// is returned as midnight UTC of that day.
+func Parse(s string) (t time.Time, dateOnly bool, err error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(DateLayout, s); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("jiratime: cannot parse %q", s)
+}
+
+// Value parses a field of a decoded payload, which may be a string or nil.
+// It reports false for anything that is not a timestamp or date.
+func Value(v interface{}) (t time.Time, dateOnly bool, ok bool) {
+	s, isString := v.(string)
+	if !isString || s == "" {
+		return time.Time{}, false, false
+	}
+	t, dateOnly, err := Parse(s)
+	return t, dateOnly, err == nil
+}
@@ -0,0 +1,93 @@
+package jiratime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin := time.FixedZone("", 2*60*60)
+	tests := []struct {
+		value    string
+		want     time.Time
+		dateOnly bool
+	}{
+		// Cloud and Server send milliseconds and a +0000 offset
+		{"2026-10-17T12:34:56.789+0000", time.Date(2026, 10, 17, 12, 34, 56, 789e6, time.UTC), false},
+		{"2026-10-17T14:34:56.789+0200", time.Date(2026, 10, 17, 14, 34, 56, 789e6, berlin), false},
+		{"2026-10-17T12:34:56.000-0500", time.Date(2026, 10, 17, 17, 34, 56, 0, time.UTC), false},
+		{"2026-10-17T12:34:56+0000", time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC), false},
+		{"2026-10-17T12:34:56.7Z", time.Date(2026, 10, 17, 12, 34, 56, 7e8, time.UTC), false},
+		{"2026-10-17T14:34:56+02:00", time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC), false},
+		{"2026-10-17T12:34+0000", time.Date(2026, 10, 17, 12, 34, 0, 0, time.UTC), false},
+		{"2026-10-17 12:34:56+0000", time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC), false},
+		{" 2026-10-17T12:34:56.789+0000\n", time.Date(2026, 10, 17, 12, 34, 56, 789e6, time.UTC), false},
+
+		// Dates are midnight UTC
+		{"2026-10-17", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), true},
+		{"2026-02-28", time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		got, dateOnly, err := Parse(tt.value)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) || dateOnly != tt.dateOnly {
+			t.Errorf("Parse(%q) = %s, %v, want %s, %v", tt.value, got, dateOnly, tt.want, tt.dateOnly)
+		}
+	}
+}
+
+func TestParseKeepsOffset(t *testing.T) {
+	got, _, err := Parse("2026-10-17T14:34:56.789+0200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := got.Zone(); offset != 2*60*60 {
+		t.Errorf("offset = %ds, want 7200s", offset)
+	}
+	if got.Format(Layout) != "2026-10-17T14:34:56.789+0200" {
+		t.Errorf("Format(Layout) = %s", got.Format(Layout))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"yesterday",
+		"17/10/2026",
+		"2026-13-01",
+		"2026-02-30",
+		"2026-10-17T25:00:00.000+0000",
+		"2026-10-17T12:34:56.789",
+		"2026-10-17T12:34:56.789+00",
+		"1792281600000",
+	} {
+		if got, _, err := Parse(value); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		ok       bool
+		dateOnly bool
+	}{
+		{nil, false, false},
+		{"", false, false},
+		{1792281600000.0, false, false},
+		{map[string]interface{}{"name": "2026-10-17"}, false, false},
+		{"not a date", false, false},
+		{"2026-10-17", true, true},
+		{"2026-10-17T12:34:56.789+0000", true, false},
+	}
+	for _, tt := range tests {
+		_, dateOnly, ok := Value(tt.value)
+		if ok != tt.ok || dateOnly != tt.dateOnly {
+			t.Errorf("Value(%#v) = %v, %v, want %v, %v", tt.value, dateOnly, ok, tt.dateOnly, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Package jql parses and evaluates a subset of the Jira Query Language
+// against the fields of an issue in a webhook body, so that filters written
+// for Jira can select events without calling Jira.
+//
+// The fields are project, issuetype (or type), priority, status, labels,
+// component, assignee and reporter. They are compared with =, !=, IN,
+// NOT IN, IS EMPTY and IS NOT EMPTY, and clauses are combined with AND, OR,
+// NOT and parentheses. An ORDER BY clause is accepted and ignored.
+package jql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Query is a parsed query.
+type Query struct {
+	source string
+	root   node
+}
+
+// Parse parses query. An empty query matches every issue.
+func Parse(query string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, fmt.Errorf("jql %q: %v", query, err)
+	}
+	p := &parser{tokens: tokens}
+	q := &Query{source: query}
+	if p.peek().kind != tokEOF && !p.peek().is("order") {
+		if q.root, err = p.or(); err != nil {
+			return nil, fmt.Errorf("jql %q: %v", query, err)
+		}
+	}
+	if err := p.orderBy(); err != nil {
+		return nil, fmt.Errorf("jql %q: %v", query, err)
+	}
+	return q, nil
+}
+
+// Match reports whether the issue with the given fields, the "fields"
+// object of a webhook's issue decoded into maps, meets the query.
+func (q *Query) Match(fields map[string]interface{}) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.eval(fields)
+}
+
+// String returns the query as it was written.
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.source
+}
+
+// MarshalText returns the query as it was written.
+func (q *Query) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText parses a query, so that a Query can be read from JSON.
+func (q *Query) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*q = *parsed
+	return nil
+}
+
+// fieldIDs maps each supported field name to the keys that identify one of
+// its values. JQL accepts either a name or an ID, and for users an account
+// ID, a username or an email.
+var fieldIDs = map[string][]string{
+	"project":   {"key", "name", "id"},
+	"issuetype": {"name", "id"},
+	"priority":  {"name", "id"},
+	"status":    {"name", "id"},
+	"labels":    nil,
+	"component": {"name", "id"},
+	"assignee":  {"accountId", "name", "key", "emailAddress", "displayName"},
+	"reporter":  {"accountId", "name", "key", "emailAddress", "displayName"},
+}
+
+// aliases are other names of fields, mapped to the key of the issue field.
+var aliases = map[string]string{
+	"type":       "issuetype",
+	"components": "component",
+}
+
+// issueKeys maps field names to the keys of the webhook's issue fields
+// where they differ.
+var issueKeys = map[string]string{
+	"component": "components",
+}
+
+type node interface {
+	eval(fields map[string]interface{}) bool
+}
+
+type and struct{ left, right node }
+
+func (n and) eval(f map[string]interface{}) bool { return n.left.eval(f) && n.right.eval(f) }
+
+type or struct{ left, right node }
+
+func (n or) eval(f map[string]interface{}) bool { return n.left.eval(f) || n.right.eval(f) }
+
+type not struct{ operand node }
+
+func (n not) eval(f map[string]interface{}) bool { return !n.operand.eval(f) }
+
+// clause compares one field with a list of values. For = and != the list
+// has one value. An empty string in the list stands for EMPTY.
+type clause struct {
+	field  string
+	negate bool
+	values []string
+}
+
+// eval follows JQL: a negated clause, such as != or NOT IN, does not match
+// an issue whose field is empty unless EMPTY is one of the values.
+func (c clause) eval(f map[string]interface{}) bool {
+	items := issueValues(f, c.field)
+	matched := false
+	for _, want := range c.values {
+		if want == "" {
+			matched = matched || len(items) == 0
+			continue
+		}
+		for _, ids := range items {
+			for _, id := range ids {
+				if strings.EqualFold(id, want) {
+					matched = true
+				}
+			}
+		}
+	}
+	if !c.negate {
+		return matched
+	}
+	if len(items) == 0 {
+		return false
+	}
+	return !matched
+}
+
+// issueValues returns the identifiers of each value of field in the issue
+// fields. A field such as labels or component can have several values.
+func issueValues(f map[string]interface{}, field string) [][]string {
+	key := field
+	if k, ok := issueKeys[field]; ok {
+		key = k
+	}
+	var list []interface{}
+	switch v := f[key].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		list = v
+	default:
+		list = []interface{}{v}
+	}
+	var items [][]string
+	for _, item := range list {
+		var ids []string
+		switch item := item.(type) {
+		case string:
+			ids = []string{item}
+		case map[string]interface{}:
+			for _, k := range fieldIDs[field] {
+				if s, ok := item[k].(string); ok && s != "" {
+					ids = append(ids, s)
+				}
+			}
+		}
+		if len(ids) > 0 {
+			items = append(items, ids)
+		}
+	}
+	return items
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) unexpected(want string) error {
+	t := p.peek()
+	return fmt.Errorf("expected %s at position %d, found %s", want, t.pos+1, t)
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	switch t := p.peek(); {
+	case t.is("not"):
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand}, nil
+	case t.kind == tokLParen:
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.unexpected(`")"`)
+		}
+		p.next()
+		return n, nil
+	}
+	return p.clause()
+}
+
+func (p *parser) clause() (node, error) {
+	t := p.peek()
+	if t.kind != tokWord && t.kind != tokString {
+		return nil, p.unexpected("a field")
+	}
+	name := strings.ToLower(t.text)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	if _, ok := fieldIDs[name]; !ok {
+		return nil, fmt.Errorf("field %q at position %d is not supported, use one of %s", t.text, t.pos+1, supported())
+	}
+	p.next()
+
+	c := clause{field: name}
+	switch op := p.next(); {
+	case op.kind == tokEquals || op.kind == tokNotEquals:
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		c.negate = op.kind == tokNotEquals
+		c.values = []string{v}
+	case op.is("in"):
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		c.values = values
+	case op.is("not"):
+		if !p.peek().is("in") {
+			return nil, p.unexpected("IN")
+		}
+		p.next()
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		c.negate, c.values = true, values
+	case op.is("is"):
+		if p.peek().is("not") {
+			p.next()
+			c.negate = true
+		}
+		if !p.peek().is("empty") && !p.peek().is("null") {
+			return nil, p.unexpected("EMPTY")
+		}
+		p.next()
+		c.values = []string{""}
+	default:
+		return nil, fmt.Errorf("expected =, !=, IN, NOT IN or IS at position %d, found %s", op.pos+1, op)
+	}
+	return c, nil
+}
+
+// value reads a word or string. EMPTY and NULL are returned as "".
+func (p *parser) value() (string, error) {
+	t := p.peek()
+	switch {
+	case t.is("empty") || t.is("null"):
+		p.next()
+		return "", nil
+	case t.kind == tokString:
+		p.next()
+		return t.text, nil
+	case t.kind == tokWord && !isKeyword(t):
+		p.next()
+		return t.text, nil
+	}
+	return "", p.unexpected("a value")
+}
+
+func (p *parser) list() ([]string, error) {
+	if p.peek().kind != tokLParen {
+		return nil, p.unexpected(`"("`)
+	}
+	p.next()
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if p.peek().kind != tokRParen {
+		return nil, p.unexpected(`"," or ")"`)
+	}
+	p.next()
+	return values, nil
+}
+
+// orderBy skips an ORDER BY clause, which means nothing for one issue, and
+// checks that the query ends.
+func (p *parser) orderBy() error {
+	if p.peek().is("order") {
+		p.next()
+		if !p.peek().is("by") {
+			return p.unexpected("BY")
+		}
+		for p.peek().kind != tokEOF {
+			p.next()
+		}
+	}
+	if p.peek().kind != tokEOF {
+		return p.unexpected("AND, OR or the end of the query")
+	}
+	return nil
+}
+
+func isKeyword(t token) bool {
+	for _, kw := range []string{"and", "or", "not", "in", "is", "order"} {
+		if t.is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func supported() string {
+	var names []string
+	for name := range fieldIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
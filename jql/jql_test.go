@@ -0,0 +1,167 @@
+package jql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// issue is the fields object of a webhook's issue.
+const issue = `{
+	"project": {"key": "PAY", "name": "Payments", "id": "10001"},
+	"issuetype": {"name": "Bug", "id": "1"},
+	"priority": {"name": "High", "id": "2"},
+	"status": {"name": "In Progress", "id": "3"},
+	"labels": ["checkout", "ui"],
+	"components": [{"name": "Web", "id": "20"}, {"name": "API", "id": "21"}],
+	"assignee": {"accountId": "acc-bob", "displayName": "Bob Smith", "emailAddress": "bob@example.com"},
+	"reporter": null
+}`
+
+func fields(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var f map[string]interface{}
+	if err := json.Unmarshal([]byte(issue), &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"ORDER BY created DESC", true},
+
+		// = and !=
+		{"project = PAY", true},
+		{"project = payments", true},
+		{"project = 10001", true},
+		{"project = OPS", false},
+		{"project != OPS", true},
+		{"project != PAY", false},
+		{"type = bug", true},
+		{"issuetype = Task", false},
+		{"labels = ui", true},
+		{"labels != ui", false},
+		{"component = API", true},
+		{"components = Mobile", false},
+		{"assignee = bob@example.com", true},
+		{`assignee = "Bob Smith"`, true},
+
+		// IN and NOT IN
+		{"priority IN (Highest, High)", true},
+		{"priority in (Low, Lowest)", false},
+		{"priority NOT IN (Low, Lowest)", true},
+		{"priority NOT IN (High)", false},
+		{"labels IN (backend, checkout)", true},
+		{"component NOT IN (Web)", false},
+
+		// Empty fields
+		{"reporter IS EMPTY", true},
+		{"reporter IS NULL", true},
+		{"reporter IS NOT EMPTY", false},
+		{"assignee IS EMPTY", false},
+		{"assignee IS NOT EMPTY", true},
+		{"reporter = EMPTY", true},
+		{"reporter IN (EMPTY, jsmith)", true},
+		{"reporter != jsmith", false},
+		{"reporter NOT IN (jsmith)", false},
+
+		// AND binds tighter than OR, NOT tighter than AND
+		{"project = OPS AND priority = High OR labels = ui", true},
+		{"project = OPS AND (priority = High OR labels = ui)", false},
+		{"labels = ui OR project = OPS AND priority = Low", true},
+		{"NOT project = OPS AND priority = High", true},
+		{"NOT (project = PAY AND priority = High)", false},
+		{"NOT NOT project = PAY", true},
+		{"project = PAY and priority = High and status = \"In Progress\"", true},
+
+		// Quoting
+		{`status = "In Progress"`, true},
+		{`status = 'in progress'`, true},
+		{`status = "In \"Progress\""`, false},
+		{`"project" = PAY`, true},
+		{`labels = "OR"`, false},
+		{"project = PAY ORDER BY priority", true},
+	}
+	f := fields(t)
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(f); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNilQuery(t *testing.T) {
+	var q *Query
+	if !q.Match(fields(t)) {
+		t.Error("a nil query does not match")
+	}
+	if q.String() != "" {
+		t.Errorf("String() = %q, want empty", q.String())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"project", "expected =, !=, IN, NOT IN or IS at position 8, found end of query"},
+		{"project = ", "expected a value at position 11, found end of query"},
+		{"project = AND", "expected a value at position 11, found AND"},
+		{"project ~ PAY", `unexpected "~" at position 9`},
+		{"project ! PAY", `unexpected "!" at position 9`},
+		{"fixVersion = 2.4", `field "fixVersion" at position 1 is not supported`},
+		{"project = PAY AND", "expected a field at position 18, found end of query"},
+		{"(project = PAY", `expected ")" at position 15, found end of query`},
+		{"project IN PAY", `expected "(" at position 12, found PAY`},
+		{"project IN (PAY OPS)", `expected "," or ")" at position 17, found OPS`},
+		{"project NOT PAY", "expected IN at position 13, found PAY"},
+		{"assignee IS bob", "expected EMPTY at position 13, found bob"},
+		{"project = PAY priority = High", "expected AND, OR or the end of the query at position 15, found priority"},
+		{"project = PAY ORDER priority", "expected BY at position 21, found priority"},
+		{`status = "In Progress`, "unterminated string at position 10"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error %q", tt.query, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var v struct {
+		Filter *Query `json:"filter"`
+	}
+	if err := json.Unmarshal([]byte(`{"filter": "priority IN (High, Highest)"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Filter.Match(fields(t)) {
+		t.Error("unmarshaled filter does not match")
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"filter":"priority IN (High, Highest)"}`; string(out) != want {
+		t.Errorf("Marshal = %s, want %s", out, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"filter": "priority ="}`), &v); err == nil {
+		t.Error("unmarshaling an invalid filter succeeded")
+	}
+}
@@ -0,0 +1,119 @@
+package jql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Kinds of token.
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+	tokComma
+	tokEquals
+	tokNotEquals
+)
+
+type token struct {
+	kind int
+	text string
+	// pos is the byte offset of the token in the query.
+	pos int
+}
+
+// is reports whether t is the unquoted keyword kw, ignoring case.
+func (t token) is(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return t.text
+}
+
+// lex splits a query into tokens, ending with a tokEOF.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{tokEquals, "=", i})
+			i++
+		case c == '!':
+			if i+1 >= len(query) || query[i+1] != '=' {
+				return nil, fmt.Errorf("unexpected %q at position %d", "!", i+1)
+			}
+			tokens = append(tokens, token{tokNotEquals, "!=", i})
+			i += 2
+		case c == '"' || c == '\'':
+			s, n, err := lexString(query[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, i+1)
+			}
+			tokens = append(tokens, token{tokString, s, i})
+			i += n
+		default:
+			start := i
+			for i < len(query) && isWordByte(query[i]) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected %q at position %d", query[i:i+1], i+1)
+			}
+			tokens = append(tokens, token{tokWord, query[start:i], start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(query)}), nil
+}
+
+// lexString reads the quoted string at the start of s and returns its
+// value and length. A backslash escapes the next character.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// isWordByte reports whether c can be part of an unquoted word. Words hold
+// keys such as PROJ, names such as In-Progress and emails.
+func isWordByte(c byte) bool {
+	if c >= 0x80 {
+		return true
+	}
+	r := rune(c)
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.@+:/", r)
+}
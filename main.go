@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	// Lambda's runtime images do not all have a zone database
+	_ "time/tzdata"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	commentcreated "zogoapps/comments/created"
+	"zogoapps/config"
+	"zogoapps/dispatcher"
+	issuecreated "zogoapps/issue/created"
+	"zogoapps/issue/deleted"
+	"zogoapps/issue/updated"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(runReplay(os.Args[2:], os.Stdout, os.Stderr))
+		case "render":
+			os.Exit(runRender(os.Args[2:], os.Stdout, os.Stderr))
+		case "serve":
+			os.Exit(runServe(os.Args[2:], os.Stderr))
+		}
+	}
+	// RUN_MODE lets container images pick HTTP mode without changing the command
+	if os.Getenv("RUN_MODE") == "serve" {
+		os.Exit(runServe(nil, os.Stderr))
+	}
+
+	dispatcher.Configure(config.Load())
+	registerHandlers()
+
+	lambda.Start(dispatcher.LambdaHandler)
+}
+
+// registerHandlers tells the dispatcher how to render each event.
+func registerHandlers() {
+	dispatcher.Register("jira:issue_created", issuecreated.Render)
+	dispatcher.Register("jira:issue_updated", updated.Render)
+	dispatcher.Register("jira:issue_deleted", deleted.Render)
+	dispatcher.Register("comment_created", commentcreated.Render)
+	dispatcher.Register("comment_updated", commentcreated.Render)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+	"zogoapps/dispatcher"
+	"zogoapps/rules"
+)
+
+const renderUsage = `Usage:
+  %s render [-send] [-channel URL] [-locale LOCALE] FILE|DIR...
+
+render runs saved Jira webhook bodies through the same decode and render path
+as the Lambda and prints the Cliq message JSON that would be sent to each
+channel of the route it takes. Every *.json file in a directory is rendered.
+With -send the messages are also posted, to -channel or else to the channels
+of the route, using ZOHO_CLIQ_API_TOKEN. Direct messages go to -channel too
+when it is set, so that a preview never reaches the people themselves. -locale renders in another language
+than the channels' own.
+`
+
+// runRender implements the render command and returns the exit code.
+func runRender(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprintf(stderr, renderUsage, os.Args[0]) }
+	send := flags.Bool("send", false, "post the rendered messages to Cliq")
+	channel := flags.String("channel", "", "channel endpoint to post to with -send (default the route's channels)")
+	locale := flags.String("locale", "", "locale to render in (default the channel's locale)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	// Rendering only needs part of the configuration, so problems elsewhere
+	// are reported but do not stop a dry run
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+	registerHandlers()
+
+	if *locale != "" && !cfg.Catalogs.Has(*locale) {
+		fmt.Fprintf(stderr, "no message catalog for locale %q\n", *locale)
+		return 2
+	}
+	if *send && cfg.CliqAPIToken == "" {
+		fmt.Fprintln(stderr, "-send needs ZOHO_CLIQ_API_TOKEN")
+		return 2
+	}
+	opts := renderOptions{send: *send, endpoint: *channel, locale: *locale}
+
+	files, err := webhookFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	failed := 0
+	for _, file := range files {
+		if len(files) > 1 {
+			fmt.Fprintf(stdout, "==> %s <==\n", file)
+		}
+		if err := renderFile(cfg, opts, file, stdout); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// renderOptions are the flags of the render command that apply to each file.
+type renderOptions struct {
+	send     bool
+	endpoint string
+	locale   string
+}
+
+// renderFile renders one webhook body for each channel of its route and,
+// with opts.send, sends the messages.
+func renderFile(cfg *config.Config, opts renderOptions, file string, stdout io.Writer) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	plan, err := dispatcher.Decide(cfg, string(body))
+	if err != nil {
+		return err
+	}
+	if plan.Action == rules.Drop {
+		if plan.Rule == "" {
+			fmt.Fprintln(stdout, "--> dropped by the default action of the rules <--")
+		} else {
+			fmt.Fprintf(stdout, "--> dropped by rule %q <--\n", plan.Rule)
+		}
+		return nil
+	}
+	route := plan.Route
+	for _, ch := range plan.Filtered {
+		fmt.Fprintf(stdout, "--> route %q, channel %q: filtered out by %q <--\n", route.Name, ch.Name, ch.Filter)
+	}
+
+	for _, ch := range route.Channels {
+		if len(route.Channels) > 1 || cfg.RoutesFile != "" || plan.Rule != "" {
+			fmt.Fprintf(stdout, "--> route %q, channel %q <--\n", route.Name, ch.Name)
+		}
+		if opts.locale != "" {
+			ch.Locale = opts.locale
+		}
+		_, message, err := dispatcher.Render(cfg, ch, string(body))
+		if err != nil {
+			return err
+		}
+		message = plan.Apply(message)
+
+		out, err := json.MarshalIndent(message, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+
+		if !opts.send {
+			continue
+		}
+		endpoint := ch.Endpoint
+		if opts.endpoint != "" {
+			endpoint = opts.endpoint
+		}
+		if endpoint == "" {
+			return fmt.Errorf("channel %q has no endpoint, set CHANNEL_ENDPOINT or use -channel", ch.Name)
+		}
+		resp, err := cliq.NewClient(endpoint, cfg.CliqAPIToken).Send(context.Background(), message)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "sent: %d\n", resp.StatusCode)
+	}
+
+	for _, dm := range plan.Direct {
+		ch := dm.Channel
+		fmt.Fprintf(stdout, "--> direct message %q (%s) <--\n", ch.Name, dm.Reason)
+		if opts.locale != "" {
+			ch.Locale = opts.locale
+		}
+		_, message, err := dispatcher.Render(cfg, ch, string(body))
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(message, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+
+		if !opts.send {
+			continue
+		}
+		endpoint := ch.Endpoint
+		if opts.endpoint != "" {
+			endpoint = opts.endpoint
+		}
+		resp, err := cliq.NewClient(endpoint, cfg.CliqAPIToken).Send(context.Background(), message)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "sent: %d\n", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookFiles expands directories to the *.json files they contain.
+func webhookFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+				found = append(found, filepath.Join(arg, e.Name()))
+			}
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"zogoapps/config"
+)
+
+// recorder counts the messages posted to it by path.
+type recorder struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (r *recorder) server(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+		r.mu.Lock()
+		r.paths = append(r.paths, req.URL.Path)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.paths)
+}
+
+// TestRenderSendChannelKeepsDirectMessages checks that render -send
+// -channel posts direct messages to the preview channel, not to the people
+// they are for.
+func TestRenderSendChannelKeepsDirectMessages(t *testing.T) {
+	var channel, people, preview recorder
+	channelSrv, peopleSrv, previewSrv := channel.server(t), people.server(t), preview.server(t)
+
+	dir := t.TempDir()
+	idmap := filepath.Join(dir, "idmap.csv")
+	if err := os.WriteFile(idmap, []byte("jira,cliq\nacc-bob,444\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(dir, "assigned.json")
+	body := `{"webhookEvent": "jira:issue_created",
+		"user": {"accountId": "acc-ann", "displayName": "Ann"},
+		"issue": {"key": "P-1", "fields": {"summary": "S", "project": {"name": "Proj"},
+			"issuetype": {"name": "Bug"}, "assignee": {"accountId": "acc-bob", "displayName": "Bob"}}}}`
+	if err := os.WriteFile(hook, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ZOHO_CLIQ_API_TOKEN", "token")
+	t.Setenv("JIRA_URL", "https://jira.example.com")
+	t.Setenv("LAMBDA_CRED", "secret")
+	t.Setenv("CHANNEL_ENDPOINT", channelSrv.URL+"/channel")
+	t.Setenv("IDENTITY_MAP_FILE", idmap)
+	t.Setenv("DM_ENDPOINT", peopleSrv.URL+"/buddies/{user}/message")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerHandlers()
+
+	var out strings.Builder
+	opts := renderOptions{send: true, endpoint: previewSrv.URL + "/preview"}
+	if err := renderFile(cfg, opts, hook, &out); err != nil {
+		t.Fatalf("renderFile: %v", err)
+	}
+	if !strings.Contains(out.String(), `--> direct message "dm:Bob" (assigned) <--`) {
+		t.Errorf("output does not show the direct message:\n%s", out.String())
+	}
+	if n := people.count(); n != 0 {
+		t.Errorf("%d messages reached DM_ENDPOINT, want none", n)
+	}
+	if n := channel.count(); n != 0 {
+		t.Errorf("%d messages reached CHANNEL_ENDPOINT, want none", n)
+	}
+	if n := preview.count(); n != 2 {
+		t.Errorf("%d messages reached -channel, want the channel message and the direct message", n)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"zogoapps/cliq"
+	"zogoapps/config"
+	"zogoapps/deadletter"
+)
+
+const replayUsage = `Usage:
+  %[1]s replay list
+  %[1]s replay show ID
+  %[1]s replay send [-endpoint URL] [-keep] [-temporary] (-all | ID...)
+
+The dead-letter store is configured with the same DEAD_LETTER_* variables as
+the Lambda. send needs ZOHO_CLIQ_API_TOKEN and, by default, posts each message
+to the channel it was originally meant for. Entries that are delivered are
+removed from the store unless -keep is given.
+
+Entries of temporary failures are skipped unless -temporary is given. Jira
+redelivered their webhooks, so their messages may already have arrived.
+`
+
+// runReplay implements the replay command and returns the exit code.
+func runReplay(args []string, stdout io.Writer, stderr io.Writer) int {
+	usage := func() int {
+		fmt.Fprintf(stderr, replayUsage, os.Args[0])
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+
+	dl, err := config.LoadDeadLetter()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	store, err := deadletter.Open(dl)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if store == nil {
+		fmt.Fprintln(stderr, "DEAD_LETTER_STORE is not set, there is nothing to replay")
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		return replayList(ctx, store, stdout, stderr)
+	case "show":
+		if len(args) != 2 {
+			return usage()
+		}
+		return replayShow(ctx, store, args[1], stdout, stderr)
+	case "send":
+		return replaySend(ctx, store, args[1:], stdout, stderr)
+	default:
+		return usage()
+	}
+}
+
+func replayList(ctx context.Context, store deadletter.Store, stdout io.Writer, stderr io.Writer) int {
+	entries, err := store.List(ctx)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tEVENT\tTEMPORARY\tERROR")
+	for _, e := range entries {
+		temporary := "no"
+		if e.Temporary {
+			temporary = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.CreatedAt.Format("2006-01-02 15:04:05"), e.Event, temporary, shorten(e.Error, 80))
+	}
+	w.Flush()
+	return 0
+}
+
+func replayShow(ctx context.Context, store deadletter.Store, id string, stdout io.Writer, stderr io.Writer) int {
+	e, err := store.Get(ctx, id)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", id, err)
+		return 1
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintln(stdout, string(data))
+	return 0
+}
+
+func replaySend(ctx context.Context, store deadletter.Store, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay send", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	endpoint := flags.String("endpoint", "", "send to this channel endpoint instead of the original one")
+	keep := flags.Bool("keep", false, "keep entries in the store after they are delivered")
+	all := flags.Bool("all", false, "send every entry in the store")
+	temporary := flags.Bool("temporary", false, "also send entries of temporary failures, which Jira redelivered")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	token := os.Getenv("ZOHO_CLIQ_API_TOKEN")
+	if token == "" {
+		fmt.Fprintln(stderr, "ZOHO_CLIQ_API_TOKEN is not set")
+		return 1
+	}
+
+	ids := flags.Args()
+	if *all {
+		entries, err := store.List(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		ids = nil
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(stderr, "no entries to send; give one or more IDs or -all")
+		return 2
+	}
+
+	failed := 0
+	for _, id := range ids {
+		sent, err := replayEntry(ctx, store, id, *endpoint, token, *keep, *temporary)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", id, err)
+			failed++
+			continue
+		}
+		if !sent {
+			fmt.Fprintf(stdout, "%s: skipped, the failure was temporary and Jira redelivered the event, so the message may already have arrived; use -temporary to send it anyway\n", id)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: delivered\n", id)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replayEntry sends one entry again and reports whether it did. An entry of
+// a temporary failure is only sent when temporary is true. On failure the
+// entry is kept with the new error so that list shows why it is still there.
+func replayEntry(ctx context.Context, store deadletter.Store, id string, endpoint string, token string, keep bool, temporary bool) (bool, error) {
+	e, err := store.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if e.Temporary && !temporary {
+		return false, nil
+	}
+	if endpoint == "" {
+		endpoint = e.Endpoint
+	}
+	if endpoint == "" {
+		return false, errors.New("the entry has no endpoint; use -endpoint")
+	}
+
+	if _, err := cliq.NewClient(endpoint, token).Send(ctx, e.Message); err != nil {
+		e.Error = err.Error()
+		if putErr := store.Put(ctx, e); putErr != nil {
+			return false, fmt.Errorf("%v (and updating the entry failed: %v)", err, putErr)
+		}
+		return false, err
+	}
+	if keep {
+		return true, nil
+	}
+	return true, store.Delete(ctx, id)
+}
+
+// shorten cuts s to at most n runes, marking the cut with an ellipsis.
+func shorten(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,254 @@
+// Package routing picks the Cliq channels an event is sent to from the
+// project, issue type, components and labels of its issue.
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"zogoapps/jql"
+)
+
+// DefaultChannel names the channel set by CHANNEL_ENDPOINT, CHANNEL_LOCALE
+// and CHANNEL_TIMEZONE. A routes file may refer to it without defining it.
+const DefaultChannel = "default"
+
+// DefaultRoute names the route taken when no other route matches.
+const DefaultRoute = "default"
+
+// Channel is a Cliq channel messages are sent to, with the settings they
+// are rendered with for it.
+type Channel struct {
+	// Name is the key of the channel in the routes file.
+	Name string `json:"-"`
+	// Endpoint is the message API URL of the channel.
+	Endpoint string `json:"endpoint"`
+	// Locale picks the message catalog, for example "de" or "ja".
+	Locale string `json:"locale,omitempty"`
+	// TimeZone is the IANA zone times are shown in, for example
+	// "Asia/Tokyo", or "" for the zone of the Jira user.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Filter is a JQL query the issue must match for the channel to get
+	// the event, or nil to send it every event of its routes.
+	Filter *jql.Query `json:"filter,omitempty"`
+	// Timeout bounds delivery to the channel, retries included, or is 0
+	// for the default of DELIVERY_TIMEOUT.
+	Timeout time.Duration `json:"-"`
+}
+
+// Issue holds what routes are matched against.
+type Issue struct {
+	Project    string
+	Type       string
+	Components []string
+	Labels     []string
+}
+
+// Route sends the events of the issues it matches to its channels. Each
+// list that is not empty must contain a value of the issue, so a route with
+// projects and labels matches issues of one of the projects that have one
+// of the labels. Values are compared without regard to case.
+type Route struct {
+	Name       string   `json:"name"`
+	Projects   []string `json:"projects,omitempty"`
+	IssueTypes []string `json:"issueTypes,omitempty"`
+	Components []string `json:"components,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	// ChannelNames are the names of the channels in the routes file.
+	ChannelNames []string `json:"channels"`
+	// Channels are the channels named by ChannelNames.
+	Channels []Channel `json:"-"`
+}
+
+// Matches reports whether issue meets every condition of the route.
+func (r Route) Matches(issue Issue) bool {
+	return anyOf(r.Projects, issue.Project) &&
+		anyOf(r.IssueTypes, issue.Type) &&
+		anyOf(r.Components, issue.Components...) &&
+		anyOf(r.Labels, issue.Labels...)
+}
+
+// anyOf reports whether want is empty or holds one of values.
+func anyOf(want []string, values ...string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, v := range values {
+			if strings.EqualFold(w, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Table is the list of routes tried for each event.
+type Table struct {
+	// Channels holds every channel by name, including DefaultChannel.
+	Channels map[string]Channel
+	// Routes are tried in order and the first that matches is taken.
+	Routes []Route
+	// Default is taken when no route matches.
+	Default Route
+}
+
+// file is the JSON layout of a routes file.
+type file struct {
+	Channels map[string]struct {
+		Channel
+		// Timeout is a duration such as "5s".
+		Timeout string `json:"timeout"`
+	} `json:"channels"`
+	Routes  []Route  `json:"routes"`
+	Default []string `json:"default"`
+}
+
+// Load reads the routes file at path. fallback is the channel set by the
+// environment. It is known as DefaultChannel unless the file defines a
+// channel of that name, and the other channels take their locale and time
+// zone from it when they do not set one. Without a "default" list in the
+// file, events no route matches go to DefaultChannel. An empty path returns
+// a table that sends everything to fallback.
+func Load(path string, fallback Channel) (*Table, error) {
+	fallback.Name = DefaultChannel
+	t := &Table{
+		Channels: map[string]Channel{DefaultChannel: fallback},
+		Default:  Route{Name: DefaultRoute, ChannelNames: []string{DefaultChannel}, Channels: []Channel{fallback}},
+	}
+	if path == "" {
+		return t, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t, fmt.Errorf("routes: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return t, fmt.Errorf("routes %s: %w", path, err)
+	}
+
+	var problems []string
+	for name, fc := range f.Channels {
+		ch := fc.Channel
+		ch.Name = name
+		if fc.Timeout != "" {
+			timeout, err := time.ParseDuration(fc.Timeout)
+			if err != nil || timeout <= 0 {
+				problems = append(problems, fmt.Sprintf("channel %q timeout must be a duration such as 5s, got %q", name, fc.Timeout))
+			}
+			ch.Timeout = timeout
+		}
+		if ch.Locale == "" {
+			ch.Locale = fallback.Locale
+		}
+		if ch.TimeZone == "" {
+			ch.TimeZone = fallback.TimeZone
+		}
+		t.Channels[name] = ch
+	}
+
+	resolve := func(r *Route) {
+		if len(r.ChannelNames) == 0 {
+			problems = append(problems, fmt.Sprintf("route %q has no channels", r.Name))
+		}
+		r.Channels = nil
+		for _, name := range r.ChannelNames {
+			ch, ok := t.Channels[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("route %q sends to unknown channel %q", r.Name, name))
+				continue
+			}
+			r.Channels = append(r.Channels, ch)
+		}
+	}
+	for i, r := range f.Routes {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		resolve(&r)
+		t.Routes = append(t.Routes, r)
+	}
+	if len(f.Default) > 0 {
+		t.Default.ChannelNames = f.Default
+	}
+	resolve(&t.Default)
+
+	if len(problems) > 0 {
+		return t, fmt.Errorf("routes %s: %s", path, strings.Join(problems, "; "))
+	}
+	return t, nil
+}
+
+// Filter returns r with only the channels whose filter matches the issue
+// fields, and the channels it left out.
+func (r Route) Filter(fields map[string]interface{}) (Route, []Channel) {
+	kept := r
+	kept.ChannelNames, kept.Channels = nil, nil
+	var skipped []Channel
+	for _, ch := range r.Channels {
+		if !ch.Filter.Match(fields) {
+			skipped = append(skipped, ch)
+			continue
+		}
+		kept.ChannelNames = append(kept.ChannelNames, ch.Name)
+		kept.Channels = append(kept.Channels, ch)
+	}
+	return kept, skipped
+}
+
+// Route returns the first route that matches issue, or the default route.
+func (t *Table) Route(issue Issue) Route {
+	for _, r := range t.Routes {
+		if r.Matches(issue) {
+			return r
+		}
+	}
+	return t.Default
+}
+
+// Named returns the route called name, which may be DefaultRoute.
+func (t *Table) Named(name string) (Route, bool) {
+	for _, r := range t.Routes {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	if name == t.Default.Name {
+		return t.Default, true
+	}
+	return Route{}, false
+}
+
+// To returns a route called name to the named channels of the table.
+func (t *Table) To(name string, channels []string) (Route, error) {
+	r := Route{Name: name, ChannelNames: channels}
+	for _, ch := range channels {
+		c, ok := t.Channels[ch]
+		if !ok {
+			return Route{}, fmt.Errorf("unknown channel %q", ch)
+		}
+		r.Channels = append(r.Channels, c)
+	}
+	return r, nil
+}
+
+// Used returns the channels that at least one route, or one of extra,
+// sends to, each once, in the order they are first named.
+func (t *Table) Used(extra ...Route) []Channel {
+	var used []Channel
+	seen := map[string]bool{}
+	routes := append(append(append([]Route{}, t.Routes...), t.Default), extra...)
+	for _, r := range routes {
+		for _, ch := range r.Channels {
+			if !seen[ch.Name] {
+				seen[ch.Name] = true
+				used = append(used, ch)
+			}
+		}
+	}
+	return used
+}
@@ -0,0 +1,221 @@
+package routing
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const routesFile = `{
+	"channels": {
+		"payments": {"endpoint": "https://cliq.example.com/payments", "locale": "de", "timeout": "5s"},
+		"web": {"endpoint": "https://cliq.example.com/web", "filter": "priority IN (Highest, High)"},
+		"mobile": {"endpoint": "https://cliq.example.com/mobile", "timeZone": "Asia/Tokyo", "filter": "labels = ios"},
+		"triage": {"endpoint": "https://cliq.example.com/triage"}
+	},
+	"routes": [
+		{"name": "payment bugs", "projects": ["PAY"], "issueTypes": ["Bug"], "channels": ["payments", "web"]},
+		{"name": "frontend", "components": ["Web", "Mobile"], "channels": ["web", "mobile"]},
+		{"labels": ["security"], "channels": ["triage", "default"]},
+		{"name": "payments", "projects": ["pay"], "channels": ["payments"]}
+	],
+	"default": ["triage"]
+}`
+
+var fallback = Channel{Endpoint: "https://cliq.example.com/all", Locale: "en", TimeZone: "Europe/Berlin"}
+
+func load(t *testing.T, routes string) (*Table, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(routes), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path, fallback)
+}
+
+func names(channels []Channel) string {
+	var out []string
+	for _, ch := range channels {
+		out = append(out, ch.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestRoute(t *testing.T) {
+	table, err := load(t, routesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		issue    Issue
+		route    string
+		channels string
+	}{
+		{Issue{Project: "PAY", Type: "Bug"}, "payment bugs", "payments,web"},
+		{Issue{Project: "pay", Type: "bug", Components: []string{"Web"}}, "payment bugs", "payments,web"},
+		{Issue{Project: "PAY", Type: "Task", Components: []string{"API", "mobile"}}, "frontend", "web,mobile"},
+		{Issue{Project: "PAY", Type: "Task"}, "payments", "payments"},
+		{Issue{Project: "OPS", Labels: []string{"infra", "Security"}}, "#3", "triage,default"},
+		{Issue{Project: "OPS", Type: "Bug"}, "default", "triage"},
+		{Issue{}, "default", "triage"},
+	}
+	for _, tt := range tests {
+		r := table.Route(tt.issue)
+		if r.Name != tt.route || names(r.Channels) != tt.channels {
+			t.Errorf("Route(%+v) = %q to %s, want %q to %s", tt.issue, r.Name, names(r.Channels), tt.route, tt.channels)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	table, err := load(t, routesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fields  string
+		kept    string
+		skipped string
+	}{
+		{`{"priority": {"name": "High"}, "labels": ["ios"]}`, "web,mobile", ""},
+		{`{"priority": {"name": "High"}, "labels": ["android"]}`, "web", "mobile"},
+		{`{"priority": {"name": "Low"}, "labels": ["ios"]}`, "mobile", "web"},
+		{`{"priority": {"name": "Low"}}`, "", "web,mobile"},
+		{`{}`, "", "web,mobile"},
+	}
+	issue := Issue{Project: "OPS", Components: []string{"Web"}}
+	for _, tt := range tests {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.fields), &fields); err != nil {
+			t.Fatal(err)
+		}
+		// A route whose filters leave no channel is still the route taken;
+		// the event does not fall through to a later route or the default
+		r := table.Route(issue)
+		kept, skipped := r.Filter(fields)
+		if kept.Name != "frontend" {
+			t.Errorf("%s: route %q, want frontend", tt.fields, kept.Name)
+		}
+		if names(kept.Channels) != tt.kept || strings.Join(kept.ChannelNames, ",") != tt.kept {
+			t.Errorf("%s: kept %s (%v), want %s", tt.fields, names(kept.Channels), kept.ChannelNames, tt.kept)
+		}
+		if names(skipped) != tt.skipped {
+			t.Errorf("%s: skipped %s, want %s", tt.fields, names(skipped), tt.skipped)
+		}
+		if names(r.Channels) != "web,mobile" {
+			t.Errorf("Filter changed the route's channels to %s", names(r.Channels))
+		}
+	}
+}
+
+func TestLoadChannels(t *testing.T) {
+	table, err := load(t, routesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		locale   string
+		timeZone string
+		timeout  time.Duration
+	}{
+		{"payments", "de", "Europe/Berlin", 5 * time.Second},
+		{"mobile", "en", "Asia/Tokyo", 0},
+		{"triage", "en", "Europe/Berlin", 0},
+		{DefaultChannel, "en", "Europe/Berlin", 0},
+	}
+	for _, tt := range tests {
+		ch, ok := table.Channels[tt.name]
+		if !ok {
+			t.Errorf("no channel %q", tt.name)
+			continue
+		}
+		if ch.Name != tt.name || ch.Locale != tt.locale || ch.TimeZone != tt.timeZone || ch.Timeout != tt.timeout {
+			t.Errorf("channel %q = %+v, want locale %s, zone %s, timeout %s", tt.name, ch, tt.locale, tt.timeZone, tt.timeout)
+		}
+	}
+	if ch := table.Channels[DefaultChannel]; ch.Endpoint != fallback.Endpoint {
+		t.Errorf("default channel endpoint = %q, want the fallback's", ch.Endpoint)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	table, err := Load("", fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Route(Issue{Project: "PAY"})
+	if r.Name != DefaultRoute || names(r.Channels) != DefaultChannel {
+		t.Errorf("Route = %q to %s, want the default route to the default channel", r.Name, names(r.Channels))
+	}
+}
+
+func TestLoadDefaultsToDefaultChannel(t *testing.T) {
+	table, err := load(t, `{"channels": {"web": {"endpoint": "https://cliq.example.com/web"}}, "routes": [{"projects": ["WEB"], "channels": ["web"]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := table.Route(Issue{Project: "PAY"}); names(r.Channels) != DefaultChannel {
+		t.Errorf("unmatched issue goes to %s, want %s", names(r.Channels), DefaultChannel)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		routes string
+		want   string
+	}{
+		{`{"routes": [{"name": "a", "channels": ["nowhere"]}]}`, `route "a" sends to unknown channel "nowhere"`},
+		{`{"routes": [{"projects": ["PAY"]}]}`, `route "#1" has no channels`},
+		{`{"default": ["nowhere"]}`, `route "default" sends to unknown channel "nowhere"`},
+		{`{"channels": {"a": {"endpoint": "x", "timeout": "soon"}}}`, `channel "a" timeout must be a duration such as 5s, got "soon"`},
+		{`{"channels": {"a": {"endpoint": "x", "timeout": "-5s"}}}`, `channel "a" timeout must be a duration`},
+		{`{"channels": {"a": {"endpoint": "x", "filter": "priority ="}}}`, "expected a value"},
+		{`[]`, "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		_, err := load(t, tt.routes)
+		if err == nil {
+			t.Errorf("%s loaded, want error %q", tt.routes, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.routes, err, tt.want)
+		}
+	}
+}
+
+func TestNamedToAndUsed(t *testing.T) {
+	table, err := load(t, routesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := table.Named("frontend"); !ok || names(r.Channels) != "web,mobile" {
+		t.Errorf("Named(frontend) = %s, %v", names(r.Channels), ok)
+	}
+	if r, ok := table.Named(DefaultRoute); !ok || names(r.Channels) != "triage" {
+		t.Errorf("Named(default) = %s, %v", names(r.Channels), ok)
+	}
+	if _, ok := table.Named("nowhere"); ok {
+		t.Error("Named(nowhere) found a route")
+	}
+
+	r, err := table.To("rule", []string{"mobile", DefaultChannel})
+	if err != nil || r.Name != "rule" || names(r.Channels) != "mobile,default" {
+		t.Errorf("To = %q to %s, %v", r.Name, names(r.Channels), err)
+	}
+	if _, err := table.To("rule", []string{"nowhere"}); err == nil {
+		t.Error("To an unknown channel succeeded")
+	}
+
+	if got := names(table.Used()); got != "payments,web,mobile,triage,default" {
+		t.Errorf("Used = %s", got)
+	}
+	extra := Route{Channels: []Channel{{Name: "web"}, {Name: "audit"}}}
+	if got := names(table.Used(extra)); got != "payments,web,mobile,triage,default,audit" {
+		t.Errorf("Used with an extra route = %s", got)
+	}
+}
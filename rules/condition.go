@@ -0,0 +1,266 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition is a test on a webhook body. It is one of:
+//
+//   - a combinator: all, any or not;
+//   - a field test: field with one of equals, in, contains, matches or
+//     exists;
+//   - a changelog test: changed or onlyChanged;
+//   - a priority test: priorityAtLeast or priorityAtMost.
+//
+// A condition with none of these matches everything.
+type Condition struct {
+	All []Condition `json:"all,omitempty"`
+	Any []Condition `json:"any,omitempty"`
+	Not *Condition  `json:"not,omitempty"`
+
+	// Field is a dotted path into the body, such as
+	// "issue.fields.priority.name". A path through a list reaches every
+	// item, so "issue.fields.components.name" holds each component's name,
+	// and the test passes if any of the values passes. Strings are compared
+	// without regard to case.
+	Field    string        `json:"field,omitempty"`
+	Equals   interface{}   `json:"equals,omitempty"`
+	In       []interface{} `json:"in,omitempty"`
+	Contains string        `json:"contains,omitempty"`
+	Matches  string        `json:"matches,omitempty"`
+	Exists   *bool         `json:"exists,omitempty"`
+
+	// Changed passes when the changelog has one of the fields, by name or
+	// ID. OnlyChanged passes when it has nothing but these fields.
+	Changed     []string `json:"changed,omitempty"`
+	OnlyChanged []string `json:"onlyChanged,omitempty"`
+
+	// PriorityAtLeast and PriorityAtMost compare the issue's priority by
+	// its place in the rules file's priorities.
+	PriorityAtLeast string `json:"priorityAtLeast,omitempty"`
+	PriorityAtMost  string `json:"priorityAtMost,omitempty"`
+
+	re   *regexp.Regexp
+	rank map[string]int
+}
+
+// compile checks the condition and its children, compiles its pattern and
+// keeps the priority ranking. It returns what is wrong.
+func (c *Condition) compile(rank map[string]int) []string {
+	c.rank = rank
+	var problems []string
+	kinds := 0
+	for _, set := range []bool{
+		len(c.All) > 0, len(c.Any) > 0, c.Not != nil, c.Field != "",
+		len(c.Changed) > 0, len(c.OnlyChanged) > 0,
+		c.PriorityAtLeast != "", c.PriorityAtMost != "",
+	} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		problems = append(problems, "a condition must have only one of all, any, not, field, changed, onlyChanged, priorityAtLeast or priorityAtMost, use all or any to combine them")
+	}
+
+	ops := 0
+	for _, set := range []bool{c.Equals != nil, len(c.In) > 0, c.Contains != "", c.Matches != "", c.Exists != nil} {
+		if set {
+			ops++
+		}
+	}
+	if c.Field != "" && ops != 1 {
+		problems = append(problems, fmt.Sprintf("field %q needs one of equals, in, contains, matches or exists", c.Field))
+	}
+	if c.Field == "" && ops > 0 {
+		problems = append(problems, "equals, in, contains, matches and exists need a field")
+	}
+	if c.Matches != "" {
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("field %q: %v", c.Field, err))
+		}
+		c.re = re
+	}
+	for _, p := range []string{c.PriorityAtLeast, c.PriorityAtMost} {
+		if _, ok := rank[strings.ToLower(p)]; p != "" && !ok {
+			problems = append(problems, fmt.Sprintf("priority %q is not in the priorities list", p))
+		}
+	}
+
+	for i := range c.All {
+		problems = append(problems, c.All[i].compile(rank)...)
+	}
+	for i := range c.Any {
+		problems = append(problems, c.Any[i].compile(rank)...)
+	}
+	if c.Not != nil {
+		problems = append(problems, c.Not.compile(rank)...)
+	}
+	return problems
+}
+
+// Match reports whether the webhook body, decoded into maps, meets the
+// condition.
+func (c *Condition) Match(payload map[string]interface{}) bool {
+	switch {
+	case len(c.All) > 0:
+		for i := range c.All {
+			if !c.All[i].Match(payload) {
+				return false
+			}
+		}
+		return true
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			if c.Any[i].Match(payload) {
+				return true
+			}
+		}
+		return false
+	case c.Not != nil:
+		return !c.Not.Match(payload)
+	case c.Field != "":
+		return c.matchField(values(payload, c.Field))
+	case len(c.Changed) > 0:
+		for _, f := range changedFields(payload) {
+			if anyFold(c.Changed, f...) {
+				return true
+			}
+		}
+		return false
+	case len(c.OnlyChanged) > 0:
+		changed := changedFields(payload)
+		for _, f := range changed {
+			if !anyFold(c.OnlyChanged, f...) {
+				return false
+			}
+		}
+		return len(changed) > 0
+	case c.PriorityAtLeast != "" || c.PriorityAtMost != "":
+		return c.matchPriority(values(payload, "issue.fields.priority.name"))
+	}
+	return true
+}
+
+func (c *Condition) matchField(vals []interface{}) bool {
+	if c.Exists != nil {
+		found := false
+		for _, v := range vals {
+			if s, ok := v.(string); !ok || s != "" {
+				found = true
+			}
+		}
+		return found == *c.Exists
+	}
+	for _, v := range vals {
+		s := str(v)
+		switch {
+		case c.Equals != nil:
+			if strings.EqualFold(s, str(c.Equals)) {
+				return true
+			}
+		case len(c.In) > 0:
+			for _, want := range c.In {
+				if strings.EqualFold(s, str(want)) {
+					return true
+				}
+			}
+		case c.Contains != "":
+			if strings.Contains(strings.ToLower(s), strings.ToLower(c.Contains)) {
+				return true
+			}
+		case c.re != nil:
+			if c.re.MatchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Condition) matchPriority(vals []interface{}) bool {
+	if len(vals) == 0 {
+		return false
+	}
+	rank, ok := c.rank[strings.ToLower(str(vals[0]))]
+	if !ok {
+		return false
+	}
+	if c.PriorityAtLeast != "" && rank > c.rank[strings.ToLower(c.PriorityAtLeast)] {
+		return false
+	}
+	if c.PriorityAtMost != "" && rank < c.rank[strings.ToLower(c.PriorityAtMost)] {
+		return false
+	}
+	return true
+}
+
+// values returns what the dotted path reaches in v, going into every item
+// of the lists on the way. Nulls are left out.
+func values(v interface{}, path string) []interface{} {
+	if path == "" {
+		switch v := v.(type) {
+		case nil:
+			return nil
+		case []interface{}:
+			var out []interface{}
+			for _, item := range v {
+				out = append(out, values(item, "")...)
+			}
+			return out
+		default:
+			return []interface{}{v}
+		}
+	}
+	key, rest, _ := strings.Cut(path, ".")
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return values(v[key], rest)
+	case []interface{}:
+		var out []interface{}
+		for _, item := range v {
+			out = append(out, values(item, path)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// changedFields returns the name and ID of each field in the changelog.
+func changedFields(payload map[string]interface{}) [][]string {
+	var fields [][]string
+	for _, item := range values(payload, "changelog.items") {
+		m, _ := item.(map[string]interface{})
+		fields = append(fields, []string{str(m["field"]), str(m["fieldId"])})
+	}
+	return fields
+}
+
+// anyFold reports whether one of values is in list, ignoring case.
+func anyFold(list []string, values ...string) bool {
+	for _, want := range list {
+		for _, v := range values {
+			if v != "" && strings.EqualFold(want, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// str returns the text of a JSON scalar, or "" for anything else.
+func str(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// event is an issue update with a changelog.
+const event = `{
+	"webhookEvent": "jira:issue_updated",
+	"issue": {"key": "PAY-7", "fields": {
+		"summary": "Checkout fails on Safari",
+		"priority": {"name": "High"},
+		"labels": ["checkout", "ui"],
+		"components": [{"name": "Web"}, {"name": "API"}],
+		"customfield_10010": 3,
+		"flagged": false,
+		"resolution": null,
+		"environment": ""
+	}},
+	"changelog": {"items": [
+		{"field": "status", "fieldId": "status"},
+		{"field": "Story Points", "fieldId": "customfield_10010"}
+	]}
+}`
+
+func decode(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("%v in %s", err, body)
+	}
+	return payload
+}
+
+func rankOf(priorities []string) map[string]int {
+	rank := map[string]int{}
+	for i, p := range priorities {
+		rank[strings.ToLower(p)] = i
+	}
+	return rank
+}
+
+func TestConditionMatch(t *testing.T) {
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{`{}`, true},
+
+		// equals
+		{`{"field": "issue.fields.priority.name", "equals": "high"}`, true},
+		{`{"field": "issue.fields.priority.name", "equals": "Low"}`, false},
+		{`{"field": "issue.fields.components.name", "equals": "api"}`, true},
+		{`{"field": "issue.fields.labels", "equals": "ui"}`, true},
+		{`{"field": "issue.fields.customfield_10010", "equals": 3}`, true},
+		{`{"field": "issue.fields.customfield_10010", "equals": "3"}`, true},
+		{`{"field": "issue.fields.flagged", "equals": false}`, true},
+		{`{"field": "issue.fields.missing", "equals": "x"}`, false},
+
+		// in
+		{`{"field": "issue.fields.priority.name", "in": ["Highest", "HIGH"]}`, true},
+		{`{"field": "issue.fields.priority.name", "in": ["Low", "Lowest"]}`, false},
+		{`{"field": "issue.fields.labels", "in": ["backend", "checkout"]}`, true},
+
+		// contains
+		{`{"field": "issue.fields.summary", "contains": "SAFARI"}`, true},
+		{`{"field": "issue.fields.summary", "contains": "Chrome"}`, false},
+		{`{"field": "issue.fields.components.name", "contains": "we"}`, true},
+
+		// matches
+		{`{"field": "issue.key", "matches": "^PAY-[0-9]+$"}`, true},
+		{`{"field": "issue.key", "matches": "^OPS-"}`, false},
+		{`{"field": "issue.fields.labels", "matches": "^u"}`, true},
+
+		// exists
+		{`{"field": "issue.fields.summary", "exists": true}`, true},
+		{`{"field": "issue.fields.summary", "exists": false}`, false},
+		{`{"field": "issue.fields.resolution", "exists": false}`, true},
+		{`{"field": "issue.fields.environment", "exists": false}`, true},
+		{`{"field": "issue.fields.missing", "exists": true}`, false},
+		{`{"field": "issue.fields.flagged", "exists": true}`, true},
+
+		// all, any and not
+		{`{"all": [{"field": "issue.key", "matches": "^PAY"}, {"field": "issue.fields.labels", "equals": "ui"}]}`, true},
+		{`{"all": [{"field": "issue.key", "matches": "^PAY"}, {"field": "issue.fields.labels", "equals": "api"}]}`, false},
+		{`{"any": [{"field": "issue.key", "matches": "^OPS"}, {"field": "issue.fields.labels", "equals": "ui"}]}`, true},
+		{`{"any": [{"field": "issue.key", "matches": "^OPS"}, {"field": "issue.fields.labels", "equals": "api"}]}`, false},
+		{`{"not": {"field": "issue.key", "matches": "^OPS"}}`, true},
+		{`{"not": {"any": [{"field": "issue.key", "matches": "^PAY"}]}}`, false},
+
+		// changed and onlyChanged, by name or ID
+		{`{"changed": ["Status"]}`, true},
+		{`{"changed": ["customfield_10010"]}`, true},
+		{`{"changed": ["story points"]}`, true},
+		{`{"changed": ["assignee"]}`, false},
+		{`{"onlyChanged": ["status", "Story Points"]}`, true},
+		{`{"onlyChanged": ["status"]}`, false},
+		{`{"onlyChanged": ["status", "customfield_10010", "assignee"]}`, true},
+
+		// priorities
+		{`{"priorityAtLeast": "High"}`, true},
+		{`{"priorityAtLeast": "critical"}`, false},
+		{`{"priorityAtLeast": "Medium"}`, true},
+		{`{"priorityAtMost": "High"}`, true},
+		{`{"priorityAtMost": "Critical"}`, true},
+		{`{"priorityAtMost": "Medium"}`, false},
+		{`{"all": [{"priorityAtLeast": "Medium"}, {"priorityAtMost": "Critical"}]}`, true},
+	}
+	payload := decode(t, event)
+	rank := rankOf(DefaultPriorities)
+	for _, tt := range tests {
+		var c Condition
+		if err := json.Unmarshal([]byte(tt.when), &c); err != nil {
+			t.Fatalf("%s: %v", tt.when, err)
+		}
+		if problems := c.compile(rank); len(problems) > 0 {
+			t.Errorf("%s: %s", tt.when, strings.Join(problems, "; "))
+			continue
+		}
+		if got := c.Match(payload); got != tt.want {
+			t.Errorf("%s matches = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
+func TestConditionWithoutChangelog(t *testing.T) {
+	payload := decode(t, `{"webhookEvent": "jira:issue_created"}`)
+	for _, when := range []string{`{"changed": ["status"]}`, `{"onlyChanged": ["status"]}`} {
+		var c Condition
+		if err := json.Unmarshal([]byte(when), &c); err != nil {
+			t.Fatal(err)
+		}
+		c.compile(rankOf(DefaultPriorities))
+		if c.Match(payload) {
+			t.Errorf("%s matches an event without a changelog", when)
+		}
+	}
+}
+
+func TestUnknownPriority(t *testing.T) {
+	rank := rankOf(DefaultPriorities)
+	for _, priority := range []string{`{"name": "Urgent"}`, `null`} {
+		payload := decode(t, `{"issue": {"fields": {"priority": `+priority+`}}}`)
+		for _, when := range []string{`{"priorityAtLeast": "Lowest"}`, `{"priorityAtMost": "Blocker"}`} {
+			var c Condition
+			if err := json.Unmarshal([]byte(when), &c); err != nil {
+				t.Fatal(err)
+			}
+			c.compile(rank)
+			if c.Match(payload) {
+				t.Errorf("%s matches priority %s", when, priority)
+			}
+		}
+	}
+}
+
+func TestConditionProblems(t *testing.T) {
+	tests := []struct {
+		when string
+		want string
+	}{
+		{`{"field": "issue.key"}`, `field "issue.key" needs one of equals, in, contains, matches or exists`},
+		{`{"field": "issue.key", "equals": "A", "contains": "B"}`, `field "issue.key" needs one of`},
+		{`{"equals": "A"}`, "need a field"},
+		{`{"field": "issue.key", "matches": "("}`, `field "issue.key": error parsing regexp`},
+		{`{"priorityAtLeast": "Urgent"}`, `priority "Urgent" is not in the priorities list`},
+		{`{"changed": ["status"], "field": "issue.key", "exists": true}`, "a condition must have only one of"},
+		{`{"not": {"all": [{"field": "issue.key"}]}}`, `field "issue.key" needs one of`},
+	}
+	for _, tt := range tests {
+		var c Condition
+		if err := json.Unmarshal([]byte(tt.when), &c); err != nil {
+			t.Fatal(err)
+		}
+		problems := strings.Join(c.compile(rankOf(DefaultPriorities)), "; ")
+		if !strings.Contains(problems, tt.want) {
+			t.Errorf("%s: problems = %q, want %q", tt.when, problems, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package rules decides, before anything is rendered, whether an event is
+// sent, dropped or sent elsewhere, from conditions on its webhook body.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Actions a rule can take.
+const (
+	// Send sends the event along its route and stops evaluating.
+	Send = "send"
+	// Drop sends nothing and stops evaluating.
+	Drop = "drop"
+	// Reroute sends the event to another route or list of channels and
+	// stops evaluating.
+	Reroute = "reroute"
+	// Mention adds mentions of users to the message and goes on to the
+	// next rule.
+	Mention = "mention"
+)
+
+// DefaultPriorities ranks Jira's built-in priorities from most to least
+// urgent, for both the Cloud and the Server priority schemes.
+var DefaultPriorities = []string{
+	"Blocker", "Highest", "Critical", "High", "Major",
+	"Medium", "Minor", "Low", "Trivial", "Lowest",
+}
+
+// Rule takes its action on the events its condition matches.
+type Rule struct {
+	Name string `json:"name"`
+	// When is the condition. A rule without one matches every event.
+	When Condition `json:"when"`
+	// Action is Send, Drop, Reroute or Mention.
+	Action string `json:"action"`
+	// Route or Channels say where Reroute sends the event: the name of a
+	// route of the routes file, or names of its channels.
+	Route    string   `json:"route,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+	// Users are who Mention mentions, as Jira account IDs, usernames or
+	// emails of the identity mapping, or as Cliq user IDs.
+	Users []string `json:"users,omitempty"`
+}
+
+// Set is the ordered list of rules of a rules file.
+type Set struct {
+	Rules []Rule `json:"rules"`
+	// Default is the action when no rule sends, drops or reroutes the
+	// event, Send or Drop.
+	Default string `json:"default"`
+	// Priorities overrides DefaultPriorities, most urgent first.
+	Priorities []string `json:"priorities"`
+
+	rank map[string]int
+}
+
+// Decision is the outcome of the rules for one event.
+type Decision struct {
+	// Action is Send, Drop or Reroute.
+	Action string
+	// Rule names the rule that chose Action, or is "" for the default.
+	Rule string
+	// Route and Channels are where Reroute sends the event.
+	Route    string
+	Channels []string
+	// Mentions are the users of every Mention rule that matched.
+	Mentions []string
+}
+
+// Load reads the rules file at path. An empty path returns nil, which sends
+// every event.
+func Load(path string) (*Set, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+	var s Set
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("rules %s: %w", path, err)
+	}
+
+	var problems []string
+	if s.Default == "" {
+		s.Default = Send
+	}
+	if s.Default != Send && s.Default != Drop {
+		problems = append(problems, fmt.Sprintf("default must be send or drop, got %q", s.Default))
+	}
+	if len(s.Priorities) == 0 {
+		s.Priorities = DefaultPriorities
+	}
+	s.rank = map[string]int{}
+	for i, p := range s.Priorities {
+		s.rank[strings.ToLower(p)] = i
+	}
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		for _, p := range r.check(s.rank) {
+			problems = append(problems, fmt.Sprintf("rule %q: %s", r.Name, p))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("rules %s: %s", path, strings.Join(problems, "; "))
+	}
+	return &s, nil
+}
+
+// check compiles the rule's condition and returns what is wrong with it.
+func (r *Rule) check(rank map[string]int) []string {
+	problems := r.When.compile(rank)
+	switch r.Action {
+	case Send, Drop:
+	case Reroute:
+		if (r.Route == "") == (len(r.Channels) == 0) {
+			problems = append(problems, "reroute needs either a route or channels")
+		}
+	case Mention:
+		if len(r.Users) == 0 {
+			problems = append(problems, "mention needs users")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("action must be send, drop, reroute or mention, got %q", r.Action))
+	}
+	return problems
+}
+
+// Evaluate runs the rules in order against a webhook body decoded into
+// maps. The first matching rule that sends, drops or reroutes decides;
+// mention rules before it add their users. A nil Set sends every event.
+func (s *Set) Evaluate(payload map[string]interface{}) Decision {
+	if s == nil {
+		return Decision{Action: Send}
+	}
+	var d Decision
+	for _, r := range s.Rules {
+		if !r.When.Match(payload) {
+			continue
+		}
+		if r.Action == Mention {
+			d.Mentions = append(d.Mentions, r.Users...)
+			continue
+		}
+		d.Action, d.Rule = r.Action, r.Name
+		d.Route, d.Channels = r.Route, r.Channels
+		return d
+	}
+	d.Action = s.Default
+	return d
+}
+
+// Reroutes returns the rules that reroute events.
+func (s *Set) Reroutes() []Rule {
+	if s == nil {
+		return nil
+	}
+	var out []Rule
+	for _, r := range s.Rules {
+		if r.Action == Reroute {
+			out = append(out, r)
+		}
+	}
+	return out
+}
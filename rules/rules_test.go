@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, rules string) (*Set, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+const rulesFile = `{
+	"priorities": ["P1", "P2", "P3", "P4"],
+	"rules": [
+		{"name": "noise", "when": {"onlyChanged": ["Rank"]}, "action": "drop"},
+		{"name": "page", "when": {"priorityAtLeast": "P1"}, "action": "mention", "users": ["acc-oncall"]},
+		{"name": "leads", "when": {"priorityAtLeast": "P2"}, "action": "mention", "users": ["lead@example.com", "777"]},
+		{"name": "security", "when": {"field": "issue.fields.labels", "equals": "security"}, "action": "reroute", "route": "secops"},
+		{"name": "web", "when": {"field": "issue.fields.components.name", "in": ["Web"]}, "action": "reroute", "channels": ["frontend", "qa"]},
+		{"name": "bugs", "when": {"field": "issue.fields.issuetype.name", "equals": "Bug"}, "action": "send"},
+		{"when": {"field": "issue.fields.issuetype.name", "equals": "Sub-task"}, "action": "drop"}
+	],
+	"default": "drop"
+}`
+
+func TestEvaluate(t *testing.T) {
+	set, err := load(t, rulesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		body string
+		want Decision
+	}{
+		{
+			name: "drop",
+			body: `{"issue": {"fields": {"priority": {"name": "P1"}}}, "changelog": {"items": [{"field": "Rank"}]}}`,
+			want: Decision{Action: Drop, Rule: "noise"},
+		},
+		{
+			name: "reroute to a route with mentions",
+			body: `{"issue": {"fields": {"priority": {"name": "P1"}, "labels": ["security"]}}}`,
+			want: Decision{Action: Reroute, Rule: "security", Route: "secops", Mentions: []string{"acc-oncall", "lead@example.com", "777"}},
+		},
+		{
+			name: "reroute to channels",
+			body: `{"issue": {"fields": {"priority": {"name": "P3"}, "components": [{"name": "Web"}]}}}`,
+			want: Decision{Action: Reroute, Rule: "web", Channels: []string{"frontend", "qa"}},
+		},
+		{
+			name: "send with a mention",
+			body: `{"issue": {"fields": {"priority": {"name": "P2"}, "issuetype": {"name": "Bug"}}}}`,
+			want: Decision{Action: Send, Rule: "bugs", Mentions: []string{"lead@example.com", "777"}},
+		},
+		{
+			name: "unnamed rule",
+			body: `{"issue": {"fields": {"issuetype": {"name": "Sub-task"}}}}`,
+			want: Decision{Action: Drop, Rule: "#7"},
+		},
+		{
+			name: "default",
+			body: `{"issue": {"fields": {"issuetype": {"name": "Task"}}}}`,
+			want: Decision{Action: Drop},
+		},
+		{
+			name: "default keeps mentions",
+			body: `{"issue": {"fields": {"priority": {"name": "P1"}, "issuetype": {"name": "Task"}}}}`,
+			want: Decision{Action: Drop, Mentions: []string{"acc-oncall", "lead@example.com", "777"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := set.Evaluate(decode(t, tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Evaluate = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNilSet(t *testing.T) {
+	set, err := Load("")
+	if err != nil || set != nil {
+		t.Fatalf("Load(\"\") = %v, %v, want nil", set, err)
+	}
+	if d := set.Evaluate(decode(t, event)); d.Action != Send {
+		t.Errorf("nil set decides %+v, want send", d)
+	}
+	if set.Reroutes() != nil {
+		t.Error("nil set has reroutes")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	set, err := load(t, `{"rules": [{"when": {"priorityAtMost": "Trivial"}, "action": "drop"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Default != Send {
+		t.Errorf("default = %q, want send", set.Default)
+	}
+	if !reflect.DeepEqual(set.Priorities, DefaultPriorities) {
+		t.Errorf("priorities = %v, want the default ones", set.Priorities)
+	}
+	if d := set.Evaluate(decode(t, `{"issue": {"fields": {"priority": {"name": "Lowest"}}}}`)); d.Action != Drop {
+		t.Errorf("Lowest decides %+v, want drop", d)
+	}
+	if d := set.Evaluate(decode(t, `{"issue": {"fields": {"priority": {"name": "Major"}}}}`)); d.Action != Send {
+		t.Errorf("Major decides %+v, want send", d)
+	}
+}
+
+func TestReroutes(t *testing.T) {
+	set, err := load(t, rulesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range set.Reroutes() {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "security,web" {
+		t.Errorf("Reroutes = %s, want security,web", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		rules string
+		want  string
+	}{
+		{`{"default": "reroute"}`, `default must be send or drop, got "reroute"`},
+		{`{"rules": [{"name": "x", "action": "notify"}]}`, `rule "x": action must be send, drop, reroute or mention, got "notify"`},
+		{`{"rules": [{"name": "x", "action": "reroute"}]}`, `rule "x": reroute needs either a route or channels`},
+		{`{"rules": [{"name": "x", "action": "reroute", "route": "a", "channels": ["b"]}]}`, `rule "x": reroute needs either a route or channels`},
+		{`{"rules": [{"name": "x", "action": "mention"}]}`, `rule "x": mention needs users`},
+		{`{"rules": [{"action": "drop", "when": {"priorityAtLeast": "High"}}], "priorities": ["P1"]}`, `rule "#1": priority "High" is not in the priorities list`},
+		{`{"rules": [{"action": "send", "when": {"field": "issue.key"}}]}`, `rule "#1": field "issue.key" needs one of`},
+		{`{"rules": {}}`, "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		_, err := load(t, tt.rules)
+		if err == nil {
+			t.Errorf("%s loaded, want error %q", tt.rules, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.rules, err, tt.want)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
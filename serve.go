@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"zogoapps/config"
+	"zogoapps/dispatcher"
+	"zogoapps/server"
+)
+
+// runServe implements the serve command, which answers webhooks over HTTP
+// instead of through the Lambda runtime. It returns the exit code.
+func runServe(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", envOr("LISTEN_ADDR", ":8080"), "address to listen on (LISTEN_ADDR)")
+	certFile := flags.String("tls-cert", os.Getenv("TLS_CERT_FILE"), "TLS certificate file; enables HTTPS with -tls-key (TLS_CERT_FILE)")
+	keyFile := flags.String("tls-key", os.Getenv("TLS_KEY_FILE"), "TLS private key file (TLS_KEY_FILE)")
+	requestTimeout := flags.Duration("request-timeout", 30*time.Second, "time limit for handling one webhook")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 25*time.Second, "time in-flight requests get to finish on shutdown")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		fmt.Fprintln(stderr, "-tls-cert and -tls-key must be given together")
+		return 2
+	}
+
+	dispatcher.Configure(config.Load())
+	registerHandlers()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := server.Run(ctx, server.Options{
+		Addr:            *addr,
+		TLSCertFile:     *certFile,
+		TLSKeyFile:      *keyFile,
+		RequestTimeout:  *requestTimeout,
+		ShutdownTimeout: *shutdownTimeout,
+	}, dispatcher.LambdaHandler)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// envOr returns the environment variable name, or fallback when it is unset.
+func envOr(name string, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
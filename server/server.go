@@ -0,0 +1,206 @@
+// Package server serves the Lambda handler over plain net/http, so that the
+// bridge can run on a VM or in Kubernetes as well as on AWS Lambda.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// maxBodyBytes matches the largest request payload Lambda accepts.
+const maxBodyBytes = 6 << 20
+
+// LambdaHandler is the signature shared with lambda.Start.
+type LambdaHandler func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Options configures Run.
+type Options struct {
+	// Addr is the address to listen on, for example ":8080".
+	Addr string
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// RequestTimeout bounds each request the way the function timeout bounds
+	// a Lambda invocation. Zero means no limit.
+	RequestTimeout time.Duration
+	// ShutdownTimeout is how long in-flight requests may take to finish once
+	// the server is asked to stop.
+	ShutdownTimeout time.Duration
+}
+
+// Handler adapts h to an http.Handler. Every request is converted to the
+// events.APIGatewayProxyRequest shape a Function URL would produce. Jira
+// only POSTs webhooks, so other methods are answered with 405, and bodies
+// over the Lambda limit with 413.
+func Handler(h LambdaHandler, requestTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ctx := r.Context()
+		if requestTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+			defer cancel()
+		}
+
+		event, err := NewRequest(r)
+		if err != nil {
+			log.Printf("Error reading request: %v", err)
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		resp, err := h(ctx, event)
+		if err != nil {
+			// Lambda answers an invocation error with a bare 500
+			log.Printf("Handler error: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, resp)
+	})
+}
+
+// NewRequest converts r to the API Gateway proxy request shape.
+func NewRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, fmt.Errorf("reading body: %w", err)
+	}
+
+	event := events.APIGatewayProxyRequest{
+		Resource:                        r.URL.Path,
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Path:             r.URL.Path,
+			HTTPMethod:       r.Method,
+			RequestTimeEpoch: time.Now().UnixMilli(),
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  remoteIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+	// Function URLs lower-case header names
+	for name, values := range r.Header {
+		lower := strings.ToLower(name)
+		event.Headers[lower] = strings.Join(values, ",")
+		event.MultiValueHeaders[lower] = values
+	}
+	if r.Host != "" {
+		event.Headers["host"] = r.Host
+	}
+	for name, values := range r.URL.Query() {
+		event.QueryStringParameters[name] = values[0]
+		event.MultiValueQueryStringParameters[name] = values
+	}
+
+	if utf8.Valid(body) {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+	return event, nil
+}
+
+func writeResponse(w http.ResponseWriter, resp events.APIGatewayProxyResponse) {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			log.Printf("Error decoding base64 response body: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Run serves h until ctx is cancelled and then shuts down gracefully,
+// waiting up to opts.ShutdownTimeout for in-flight requests.
+func Run(ctx context.Context, opts Options, h LambdaHandler) error {
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           Handler(h, opts.RequestTimeout),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		var err error
+		if opts.TLSCertFile != "" {
+			log.Printf("Listening on %s (HTTPS)", opts.Addr)
+			err = srv.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile)
+		} else {
+			log.Printf("Listening on %s (HTTP)", opts.Addr)
+			err = srv.ListenAndServe()
+		}
+		errc <- err
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
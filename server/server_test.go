@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// recordingHandler answers 200 and keeps the last event it was given.
+type recordingHandler struct {
+	calls int
+	event events.APIGatewayProxyRequest
+	ctx   context.Context
+}
+
+func (rh *recordingHandler) handle(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	rh.calls++
+	rh.event, rh.ctx = event, ctx
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "ok"}, nil
+}
+
+func TestHandlerMethods(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions} {
+		var rh recordingHandler
+		w := httptest.NewRecorder()
+		Handler(rh.handle, 0).ServeHTTP(w, httptest.NewRequest(method, "/", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: status %d, want 405", method, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != http.MethodPost {
+			t.Errorf("%s: Allow = %q, want POST", method, allow)
+		}
+		if rh.calls != 0 {
+			t.Errorf("%s: handler called", method)
+		}
+	}
+
+	var rh recordingHandler
+	w := httptest.NewRecorder()
+	Handler(rh.handle, 0).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+	if w.Code != http.StatusOK || rh.calls != 1 {
+		t.Errorf("POST: status %d, %d calls, want 200 and 1", w.Code, rh.calls)
+	}
+}
+
+func TestHandlerBodyLimit(t *testing.T) {
+	tests := []struct {
+		size   int
+		status int
+	}{
+		{maxBodyBytes, http.StatusOK},
+		{maxBodyBytes + 1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		var rh recordingHandler
+		w := httptest.NewRecorder()
+		body := strings.NewReader(strings.Repeat("x", tt.size))
+		Handler(rh.handle, 0).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", body))
+		if w.Code != tt.status {
+			t.Errorf("%d bytes: status %d, want %d", tt.size, w.Code, tt.status)
+		}
+		wantCalls := 0
+		if tt.status == http.StatusOK {
+			wantCalls = 1
+		}
+		if rh.calls != wantCalls {
+			t.Errorf("%d bytes: %d handler calls, want %d", tt.size, rh.calls, wantCalls)
+		}
+		if tt.status == http.StatusOK && len(rh.event.Body) != tt.size {
+			t.Errorf("%d bytes: handler got %d", tt.size, len(rh.event.Body))
+		}
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "http://bridge.example.com/hook?lamda-auth=secret&tag=a&tag=b", strings.NewReader(`{"webhookEvent":"jira:issue_created"}`))
+	r.RemoteAddr = "203.0.113.7:51234"
+	r.Header.Set("User-Agent", "Atlassian Webhook HTTP Client")
+	r.Header.Set("X-Hub-Signature", "sha256=abc")
+	r.Header.Set("X-Atlassian-Webhook-Identifier", "42")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Add("Accept", "text/plain")
+
+	event, err := NewRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name      string
+		got, want string
+	}{
+		{"method", event.HTTPMethod, http.MethodPost},
+		{"path", event.Path, "/hook"},
+		{"request context path", event.RequestContext.Path, "/hook"},
+		{"body", event.Body, `{"webhookEvent":"jira:issue_created"}`},
+		{"signature header", event.Headers["x-hub-signature"], "sha256=abc"},
+		{"webhook identifier header", event.Headers["x-atlassian-webhook-identifier"], "42"},
+		{"repeated header", event.Headers["accept"], "application/json,text/plain"},
+		{"host header", event.Headers["host"], "bridge.example.com"},
+		{"query parameter", event.QueryStringParameters["lamda-auth"], "secret"},
+		{"repeated query parameter", event.QueryStringParameters["tag"], "a"},
+		{"all of a repeated query parameter", strings.Join(event.MultiValueQueryStringParameters["tag"], ","), "a,b"},
+		{"all of a repeated header", strings.Join(event.MultiValueHeaders["accept"], ","), "application/json,text/plain"},
+		{"source IP", event.RequestContext.Identity.SourceIP, "203.0.113.7"},
+		{"user agent", event.RequestContext.Identity.UserAgent, "Atlassian Webhook HTTP Client"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+	if _, ok := event.Headers["X-Hub-Signature"]; ok {
+		t.Error("header names are not lower-cased")
+	}
+	if event.IsBase64Encoded {
+		t.Error("text body is base64 encoded")
+	}
+}
+
+func TestNewRequestBinaryBody(t *testing.T) {
+	body := []byte{0xff, 0xfe, 0x00, 0x01}
+	event, err := NewRequest(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.IsBase64Encoded || event.Body != base64.StdEncoding.EncodeToString(body) {
+		t.Errorf("body = %q, base64 %v, want it base64 encoded", event.Body, event.IsBase64Encoded)
+	}
+}
+
+func TestHandlerResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		resp   events.APIGatewayProxyResponse
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "status and headers",
+			resp:   events.APIGatewayProxyResponse{StatusCode: 503, Headers: map[string]string{"Content-Type": "text/plain"}, Body: "try again"},
+			status: 503,
+			body:   "try again",
+		},
+		{
+			name:   "no status",
+			resp:   events.APIGatewayProxyResponse{Body: "done"},
+			status: 200,
+			body:   "done",
+		},
+		{
+			name:   "base64 body",
+			resp:   events.APIGatewayProxyResponse{StatusCode: 200, Body: base64.StdEncoding.EncodeToString([]byte("decoded")), IsBase64Encoded: true},
+			status: 200,
+			body:   "decoded",
+		},
+		{
+			name:   "bad base64 body",
+			resp:   events.APIGatewayProxyResponse{StatusCode: 200, Body: "%%%", IsBase64Encoded: true},
+			status: 500,
+			body:   "Internal Server Error\n",
+		},
+		{
+			name:   "handler error",
+			err:    errors.New("boom"),
+			status: 500,
+			body:   "Internal Server Error\n",
+		},
+	}
+	for _, tt := range tests {
+		h := func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return tt.resp, tt.err
+		}
+		w := httptest.NewRecorder()
+		Handler(h, 0).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+	}
+
+	h := func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			Headers:           map[string]string{"Content-Type": "text/plain"},
+			MultiValueHeaders: map[string][]string{"X-Result": {"a", "b"}},
+		}, nil
+	}
+	w := httptest.NewRecorder()
+	Handler(h, 0).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if got := strings.Join(w.Header().Values("X-Result"), ","); got != "a,b" {
+		t.Errorf("X-Result = %q, want a,b", got)
+	}
+}
+
+func TestHandlerRequestTimeout(t *testing.T) {
+	var rh recordingHandler
+	Handler(rh.handle, 30*time.Second).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	deadline, ok := rh.ctx.Deadline()
+	if !ok || time.Until(deadline) > 30*time.Second || time.Until(deadline) < 29*time.Second {
+		t.Errorf("handler deadline = %v, %v, want 30s from now", deadline, ok)
+	}
+
+	Handler(rh.handle, 0).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if _, ok := rh.ctx.Deadline(); ok {
+		t.Error("handler has a deadline without a request timeout")
+	}
+}
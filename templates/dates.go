@@ -0,0 +1,148 @@
+package templates
+
+import (
+	"text/template"
+	"time"
+
+	"zogoapps/i18n"
+	"zogoapps/jiratime"
+)
+
+// clock formats the timestamps of one message in its time zone and
+// language.
+type clock struct {
+	loc *time.Location
+	now time.Time
+	tr  i18n.Translator
+}
+
+// now is the current time of new clocks. Tests replace it.
+var now = time.Now
+
+// TimeZone returns the zone a message for a channel shows times in: the
+// channel's own, or else the zone of the user who caused the event. It is ""
+// for UTC when neither is known.
+func TimeZone(channel string, actor string) string {
+	if channel != "" {
+		return channel
+	}
+	return actor
+}
+
+// newClock returns a clock for the IANA time zone, such as "Europe/Berlin".
+// An empty or unknown zone is UTC.
+func newClock(timeZone string, tr i18n.Translator) clock {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return clock{loc: loc, now: now(), tr: tr}
+}
+
+// funcs returns the template functions bound to c.
+func (c clock) funcs() template.FuncMap {
+	return template.FuncMap{"date": c.date, "ago": c.ago, "due": c.due}
+}
+
+// date formats a Jira timestamp or date with the layout of the catalog. It
+// returns "" when v is empty.
+//
+//	{{date .Fields.Created}}
+func (c clock) date(v interface{}) string {
+	t, dateOnly, ok := jiratime.Value(v)
+	if !ok {
+		return ""
+	}
+	if dateOnly {
+		return t.Format(c.tr("format.date"))
+	}
+	return t.In(c.loc).Format(c.tr("format.datetime"))
+}
+
+// ago describes a Jira timestamp relative to now, such as "3h ago" or
+// "in 2 days".
+//
+//	{{ago .Fields.Updated}}
+func (c clock) ago(v interface{}) string {
+	t, dateOnly, ok := jiratime.Value(v)
+	if !ok {
+		return ""
+	}
+	if dateOnly {
+		switch days := c.daysUntil(t); {
+		case days == 0:
+			return c.tr("relative.today")
+		case days > 0:
+			return c.tr("relative.in", c.dayCount(days))
+		default:
+			return c.tr("relative.ago", c.dayCount(-days))
+		}
+	}
+	d := t.Sub(c.now)
+	switch {
+	case d > -time.Minute && d < time.Minute:
+		return c.tr("relative.now")
+	case d > 0:
+		return c.tr("relative.in", c.duration(d))
+	default:
+		return c.tr("relative.ago", c.duration(-d))
+	}
+}
+
+// due describes a due date relative to now, such as "due in 2 days" or
+// "overdue by 3h". A date without a time is due by the end of that day.
+//
+//	{{due .Fields.Duedate}}
+func (c clock) due(v interface{}) string {
+	t, dateOnly, ok := jiratime.Value(v)
+	if !ok {
+		return ""
+	}
+	if dateOnly {
+		switch days := c.daysUntil(t); {
+		case days == 0:
+			return c.tr("due.today")
+		case days > 0:
+			return c.tr("due.in", c.dayCount(days))
+		default:
+			return c.tr("due.overdue", c.dayCount(-days))
+		}
+	}
+	d := t.Sub(c.now)
+	if d < 0 {
+		return c.tr("due.overdue", c.duration(-d))
+	}
+	return c.tr("due.in", c.duration(d))
+}
+
+// duration writes d in minutes under an hour, hours under two days and days
+// after that.
+func (c clock) duration(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		minutes := int(d / time.Minute)
+		if minutes < 1 {
+			minutes = 1
+		}
+		return c.tr("duration.minutes", minutes)
+	case d < 48*time.Hour:
+		return c.tr("duration.hours", int(d/time.Hour))
+	default:
+		return c.dayCount(int(d / (24 * time.Hour)))
+	}
+}
+
+func (c clock) dayCount(n int) string {
+	if n == 1 {
+		return c.tr("duration.day")
+	}
+	return c.tr("duration.days", n)
+}
+
+// daysUntil returns the number of calendar days from today, in the clock's
+// zone, to date, which is midnight UTC of a day as jiratime returns it.
+func (c clock) daysUntil(date time.Time) int {
+	y, m, d := c.now.In(c.loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(date.Sub(today).Hours() / 24)
+}
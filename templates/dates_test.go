@@ -0,0 +1,157 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"zogoapps/i18n"
+)
+
+var testNow = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func testClock(t *testing.T, timeZone string, locale string, now time.Time) clock {
+	t.Helper()
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clock{loc: loc, now: now, tr: i18n.Default.Translator(locale)}
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		zone   string
+		locale string
+		value  interface{}
+		want   string
+	}{
+		{"UTC", "en", "2026-10-17T09:30:00.000+0000", "2026-10-17 09:30 UTC"},
+		{"Europe/Berlin", "en", "2026-10-17T09:30:00.000+0000", "2026-10-17 11:30 CEST"},
+		{"Asia/Tokyo", "en", "2026-10-17T20:30:00.000+0000", "2026-10-18 05:30 JST"},
+		{"Europe/Berlin", "en", "2026-10-17T11:30:00.000+0200", "2026-10-17 11:30 CEST"},
+		{"Europe/Berlin", "de", "2026-10-17T09:30:00.000+0000", "17.10.2026 11:30 CEST"},
+		{"Asia/Tokyo", "ja", "2026-10-17T09:30:00.000+0000", "2026/10/17 18:30 JST"},
+
+		// Dates are the same day in every zone
+		{"Asia/Tokyo", "en", "2026-10-20", "2026-10-20"},
+		{"America/Los_Angeles", "de", "2026-10-20", "20.10.2026"},
+
+		{"UTC", "en", "", ""},
+		{"UTC", "en", nil, ""},
+		{"UTC", "en", "soon", ""},
+	}
+	for _, tt := range tests {
+		if got := testClock(t, tt.zone, tt.locale, testNow).date(tt.value); got != tt.want {
+			t.Errorf("date(%v) in %s, %s = %q, want %q", tt.value, tt.zone, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"2026-10-17T11:59:30.000+0000", "just now"},
+		{"2026-10-17T12:00:30.000+0000", "just now"},
+		{"2026-10-17T11:59:00.000+0000", "1 min ago"},
+		{"2026-10-17T11:30:00.000+0000", "30 min ago"},
+		{"2026-10-17T09:00:00.000+0000", "3h ago"},
+		{"2026-10-17T11:00:00.000+0200", "3h ago"},
+		{"2026-10-16T10:00:00.000+0000", "26h ago"},
+		{"2026-10-15T12:00:00.000+0000", "2 days ago"},
+		{"2026-10-17T14:00:00.000+0000", "in 2h"},
+		{"2026-10-20T12:00:00.000+0000", "in 3 days"},
+		{"2026-10-17", "today"},
+		{"2026-10-18", "in 1 day"},
+		{"2026-10-14", "3 days ago"},
+		{"", ""},
+	}
+	c := testClock(t, "UTC", "en", testNow)
+	for _, tt := range tests {
+		if got := c.ago(tt.value); got != tt.want {
+			t.Errorf("ago(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"2026-10-17", "due today"},
+		{"2026-10-18", "due in 1 day"},
+		{"2026-10-19", "due in 2 days"},
+		{"2026-10-16", "overdue by 1 day"},
+		{"2026-10-07", "overdue by 10 days"},
+		{"2026-10-17T15:00:00.000+0000", "due in 3h"},
+		{"2026-10-17T12:20:00.000+0000", "due in 20 min"},
+		{"2026-10-17T11:00:00.000+0000", "overdue by 1h"},
+		{"2026-10-17T11:59:59.500+0000", "overdue by 1 min"},
+		{"2026-10-21T12:00:00.000+0000", "due in 4 days"},
+		{nil, ""},
+	}
+	c := testClock(t, "UTC", "en", testNow)
+	for _, tt := range tests {
+		if got := c.due(tt.value); got != tt.want {
+			t.Errorf("due(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDaysInZone(t *testing.T) {
+	// At 23:30 UTC it is already the next day in Tokyo, but not yet in
+	// Los Angeles
+	late := time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		zone string
+		want string
+	}{
+		{"UTC", "due in 1 day"},
+		{"Asia/Tokyo", "due today"},
+		{"America/Los_Angeles", "due in 1 day"},
+	}
+	for _, tt := range tests {
+		if got := testClock(t, tt.zone, "en", late).due("2026-10-18"); got != tt.want {
+			t.Errorf("due(2026-10-18) in %s = %q, want %q", tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	saved := now
+	now = func() time.Time { return testNow }
+	defer func() { now = saved }()
+	t.Setenv("TEMPLATE_ISSUE_CREATED", `{{define "text"}}{{date .Fields.created}}, {{due .Fields.duedate}}{{end}}`)
+	set, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// channel is the channel's zone: the route's timeZone, or
+	// CHANNEL_TIMEZONE for the default channel. actor is the zone of the
+	// user who caused the event.
+	tests := []struct {
+		name    string
+		channel string
+		actor   string
+		want    string
+	}{
+		{"channel zone", "Asia/Tokyo", "Europe/Berlin", "2026-10-17 21:30 JST, due in 1 day"},
+		{"actor zone", "", "Europe/Berlin", "2026-10-17 14:30 CEST, due in 1 day"},
+		{"UTC", "", "", "2026-10-17 12:30 UTC, due in 1 day"},
+		{"unknown actor zone", "", "Mars/Olympus", "2026-10-17 12:30 UTC, due in 1 day"},
+	}
+	fields := map[string]interface{}{"created": "2026-10-17T12:30:00.000+0000", "duedate": "2026-10-18"}
+	for _, tt := range tests {
+		zone := TimeZone(tt.channel, tt.actor)
+		msg, err := set.Render(IssueCreated, Data{Fields: fields, Locale: "en", TimeZone: zone})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if msg.Text != tt.want {
+			t.Errorf("%s: rendered %q, want %q", tt.name, msg.Text, tt.want)
+		}
+	}
+}
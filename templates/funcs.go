@@ -0,0 +1,199 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"zogoapps/adf"
+	"zogoapps/i18n"
+	"zogoapps/identity"
+	"zogoapps/wiki"
+)
+
+// funcs are available to every template in addition to the built-in ones.
+var funcs = template.FuncMap{
+	"default":  defaultValue,
+	"join":     join,
+	"truncate": truncate,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"field":    field,
+	"excerpt":  Excerpt,
+	"has":      has,
+}
+
+// funcs returns the functions that depend on the set, such as those that
+// mention users.
+func (s *Set) funcs() template.FuncMap {
+	return template.FuncMap{
+		"markdown": s.Markdown,
+		"mention":  s.mention,
+		// Render binds these to the locale and time zone of each message
+		"t":    s.Translator(i18n.DefaultLocale),
+		"date": clock{}.date,
+		"ago":  clock{}.ago,
+		"due":  clock{}.due,
+	}
+}
+
+// defaultValue returns value, or fallback when value is empty.
+//
+//	{{default "Unassigned" .Fields.Assignee.displayName}}
+func defaultValue(fallback interface{}, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return fallback
+	}
+	return value
+}
+
+// join joins a list with sep. Items that are objects with a "name" or
+// "value" key, such as Jira components and select options, contribute that
+// key.
+func join(sep string, list interface{}) string {
+	items, ok := list.([]interface{})
+	if !ok {
+		if strs, ok := list.([]string); ok {
+			return strings.Join(strs, sep)
+		}
+		return ""
+	}
+	var parts []string
+	for _, item := range items {
+		switch v := item.(type) {
+		case map[string]interface{}:
+			if name, ok := v["name"].(string); ok {
+				parts = append(parts, name)
+			} else if value, ok := v["value"].(string); ok {
+				parts = append(parts, value)
+			}
+		case nil:
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// has reports whether list contains item.
+//
+//	{{if not (has "fields" .Slides)}}
+func has(item string, list []string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// field reads a key from a JSON object that the typed payload leaves as an
+// interface{}, such as the assignee. It returns "" when the object is null.
+//
+//	{{field .Fields.Assignee "displayName"}}
+func field(object interface{}, key string) interface{} {
+	m, ok := object.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if v, ok := m[key]; ok && v != nil {
+		return v
+	}
+	return ""
+}
+
+// Markdown converts a Jira rich-text value, such as a description or a
+// comment body, to Cliq markdown. Jira Cloud sends Atlassian Document
+// Format; Jira Server and Data Center send a string of wiki markup.
+// Mentions of users in the directory become Cliq mentions.
+//
+//	{{markdown .Fields.Description}}
+func (s *Set) Markdown(value interface{}) string {
+	var d *identity.Directory
+	if s != nil {
+		d = s.directory
+	}
+	if doc, ok := adf.Parse(value); ok {
+		return adf.ToMarkdown(doc, adf.Options{Mention: func(accountID string, text string) string {
+			if id := d.Lookup(accountID, "", ""); id != "" {
+				return identity.Mention(id)
+			}
+			if text == "" {
+				return "@" + accountID
+			}
+			return "@" + text
+		}})
+	}
+	if text, ok := value.(string); ok {
+		return wiki.ToMarkdown(text, wiki.Options{Mention: func(accountID string, username string) string {
+			if id := d.Lookup(accountID, username, ""); id != "" {
+				return identity.Mention(id)
+			}
+			return "@" + accountID + username
+		}})
+	}
+	return ""
+}
+
+// mention renders a Jira user, such as .Fields.Assignee, as a Cliq mention
+// when the directory knows them and as their display name otherwise.
+//
+//	{{mention .Fields.Reporter}}
+func (s *Set) mention(user interface{}) string {
+	u := userFields(user)
+	if id := s.directory.Lookup(u["accountId"], u["name"], u["emailAddress"]); id != "" {
+		return identity.Mention(id)
+	}
+	return u["displayName"]
+}
+
+// userFields returns the string fields of a user, whether the payload
+// decoded it into a struct or a map.
+func userFields(user interface{}) map[string]string {
+	m, ok := user.(map[string]interface{})
+	if !ok && user != nil {
+		data, _ := json.Marshal(user)
+		json.Unmarshal(data, &m)
+	}
+	fields := map[string]string{}
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+		}
+	}
+	return fields
+}
+
+// Excerpt shortens markdown to at most n runes for a preview. It cuts at a
+// space or line break where it can, marks the cut with an ellipsis and closes
+// a code block the cut leaves open. n <= 0 keeps all of s.
+//
+//	{{markdown .Comment.Body | excerpt 200}}
+func Excerpt(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	cut := string(runes[:n-1])
+	if i := strings.LastIndexAny(cut, " \n"); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	cut = strings.TrimRight(cut, " \n") + "…"
+	if strings.Count(cut, "```")%2 == 1 {
+		cut += "\n```"
+	}
+	return cut
+}
@@ -0,0 +1,112 @@
+package templates
+
+import (
+	"zogoapps/cliq"
+)
+
+// Slides that can be attached to a message, as named in SLIDES_<NAME>.
+const (
+	// SlideFields is a label slide with the type, priority, status,
+	// assignee, reporter, labels, components and fix versions of the issue.
+	SlideFields = "fields"
+	// SlideChanges is a table slide with a row for each changed field.
+	SlideChanges = "changes"
+)
+
+// DefaultSlides are the slides attached by each template when
+// SLIDES_<NAME> is not set.
+var DefaultSlides = map[string][]string{
+	IssueCreated:   {SlideFields},
+	IssueUpdated:   {SlideFields, SlideChanges},
+	IssueDeleted:   {SlideFields},
+	CommentCreated: nil,
+}
+
+// slides builds the slides listed in data.Slides, translated and with times
+// shown by c. A slide with nothing to show is left out.
+func (s *Set) slides(data Data, c clock) []cliq.Slide {
+	tr := c.tr
+	var slides []cliq.Slide
+	for _, kind := range data.Slides {
+		switch kind {
+		case SlideFields:
+			if labels := s.fieldLabels(data, c); len(labels) > 0 {
+				slides = append(slides, cliq.Slide{Type: cliq.SlideLabel, Title: tr("slide.details"), Data: labels})
+			}
+		case SlideChanges:
+			if len(data.Changes) == 0 {
+				continue
+			}
+			field, from, to := tr("label.field"), tr("label.from"), tr("label.to")
+			table := cliq.Table{Headers: []string{field, from, to}}
+			for _, c := range data.Changes {
+				table.Rows = append(table.Rows, map[string]string{
+					field: c.Field,
+					from:  defaultString(tr("value.none"), c.From),
+					to:    defaultString(tr("value.none"), c.To),
+				})
+			}
+			slides = append(slides, cliq.Slide{Type: cliq.SlideTable, Title: tr("slide.changes"), Data: table})
+		}
+	}
+	return slides
+}
+
+// fieldLabels returns the label slide data for the issue in data.Raw, one
+// single-entry map per field so that Cliq keeps them in order.
+func (s *Set) fieldLabels(data Data, c clock) []map[string]string {
+	tr := c.tr
+	fields := issueFields(data)
+	if fields == nil {
+		return nil
+	}
+	name := func(key string) string {
+		v, _ := field(fields[key], "name").(string)
+		return v
+	}
+	// An issue that was just created has nothing to add about its update
+	var updated string
+	if fields["updated"] != fields["created"] {
+		updated = withNote(c.date(fields["updated"]), c.ago(fields["updated"]))
+	}
+	status := name("status")
+	if status != "" && data.Style.StatusEmoji != "" {
+		status = data.Style.StatusEmoji + " " + status
+	}
+	labels := []struct{ key, value string }{
+		{"label.type", name("issuetype")},
+		{"label.priority", name("priority")},
+		{"label.status", status},
+		{"label.assignee", defaultString(tr("value.unassigned"), s.mention(fields["assignee"]))},
+		{"label.reporter", s.mention(fields["reporter"])},
+		{"label.due", withNote(c.date(fields["duedate"]), c.due(fields["duedate"]))},
+		{"label.created", withNote(c.date(fields["created"]), c.ago(fields["created"]))},
+		{"label.updated", updated},
+		{"label.resolved", c.date(fields["resolutiondate"])},
+		{"label.labels", join(", ", fields["labels"])},
+		{"label.components", join(", ", fields["components"])},
+		{"label.fix_versions", join(", ", fields["fixVersions"])},
+	}
+	var out []map[string]string
+	for _, l := range labels {
+		if l.value != "" {
+			out = append(out, map[string]string{tr(l.key): l.value})
+		}
+	}
+	return out
+}
+
+// withNote returns s followed by note in parentheses, or "" when s is empty.
+func withNote(s string, note string) string {
+	if s == "" || note == "" {
+		return s
+	}
+	return s + " (" + note + ")"
+}
+
+func defaultString(fallback string, s string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
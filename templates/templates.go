@@ -0,0 +1,230 @@
+// Package templates renders the text and card of each notification from
+// text/template files. Default templates are embedded in the binary, and each
+// can be overridden from a directory or from the environment.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"zogoapps/cardstyle"
+	"zogoapps/changelog"
+	"zogoapps/cliq"
+	"zogoapps/i18n"
+	"zogoapps/identity"
+)
+
+// Names of the templates, one per kind of notification. A file overriding a
+// template is called <name>.tmpl.
+const (
+	IssueCreated   = "issue_created"
+	IssueUpdated   = "issue_updated"
+	IssueDeleted   = "issue_deleted"
+	CommentCreated = "comment_created"
+)
+
+// Names lists every template the bridge renders.
+var Names = []string{IssueCreated, IssueUpdated, IssueDeleted, CommentCreated}
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Data is what a template is executed with.
+type Data struct {
+	// Event is the webhookEvent of the payload, for example "jira:issue_created".
+	Event string
+	// Payload is the whole decoded webhook body. Issue, Fields, User,
+	// Changelog and Comment are shortcuts into it.
+	Payload   interface{}
+	Issue     interface{}
+	Fields    interface{}
+	User      interface{}
+	Changelog interface{}
+	Comment   interface{}
+	// Changes lists the changed fields of an issue updated event.
+	Changes []changelog.Change
+	// Excerpt is the body of a comment as Cliq markdown, shortened to
+	// COMMENT_EXCERPT_LENGTH. CommentURL links to the comment, and Edited is
+	// set when the comment was changed after it was posted.
+	Excerpt    string
+	CommentURL string
+	Edited     bool
+	// Slides lists the slides attached to the message, such as SlideFields.
+	// Templates can leave out what a slide already shows.
+	Slides []string
+	// Locale is the locale of the channel the message is for. The "t"
+	// function translates into it.
+	Locale string
+	// TimeZone is the IANA zone, such as "Europe/Berlin", that the "date",
+	// "ago" and "due" functions show times in. UTC is used when it is empty.
+	TimeZone string
+	// Style is the look picked for the card from the issue's priority, type
+	// and status. Render sets it.
+	Style cardstyle.Style
+	// Raw is the webhook body decoded into maps, for fields the typed
+	// payload does not declare, such as custom fields.
+	Raw map[string]interface{}
+	// JiraURL is the base URL of the Jira instance and IssueURL the link to
+	// the issue.
+	JiraURL  string
+	IssueURL string
+}
+
+// Set holds the parsed templates.
+type Set struct {
+	templates map[string]*template.Template
+	directory *identity.Directory
+	styles    cardstyle.Styles
+	catalogs  *i18n.Catalogs
+}
+
+// Load parses every template. For each name, the source is taken from the
+// environment variable TEMPLATE_<NAME> (for example TEMPLATE_ISSUE_CREATED)
+// if it is set, else from <dir>/<name>.tmpl if dir is not empty and the file
+// exists, else from the embedded default. Every template must define "text";
+// "title", "theme" and "thumbnail" are optional and set the card.
+func Load(dir string) (*Set, error) {
+	set := &Set{templates: map[string]*template.Template{}, styles: cardstyle.Defaults}
+	var problems []string
+	for _, name := range Names {
+		source, origin, err := source(dir, name)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		t, err := template.New(name).Option("missingkey=zero").Funcs(funcs).Funcs(set.funcs()).Parse(source)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", origin, err))
+			continue
+		}
+		if t.Lookup("text") == nil {
+			problems = append(problems, fmt.Sprintf("%s: does not define a \"text\" template", origin))
+			continue
+		}
+		set.templates[name] = t
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return set, nil
+}
+
+// source returns the template source for name and where it came from.
+func source(dir string, name string) (string, string, error) {
+	envName := "TEMPLATE_" + strings.ToUpper(name)
+	if src := os.Getenv(envName); src != "" {
+		return src, envName, nil
+	}
+	if dir != "" {
+		path := filepath.Join(dir, name+".tmpl")
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", path, err
+		}
+	}
+	path := "defaults/" + name + ".tmpl"
+	data, err := defaults.ReadFile(path)
+	if err != nil {
+		return "", path, err
+	}
+	return string(data), "embedded " + path, nil
+}
+
+// UseDirectory makes the templates mention the Jira users that d maps to
+// Cliq users.
+func (s *Set) UseDirectory(d *identity.Directory) {
+	if s != nil {
+		s.directory = d
+	}
+}
+
+// UseCatalogs sets the message catalogs the "t" function and the slides
+// are translated from. i18n.Default is used until it is called.
+func (s *Set) UseCatalogs(c *i18n.Catalogs) {
+	if s != nil {
+		s.catalogs = c
+	}
+}
+
+// Translator returns the translator for locale.
+func (s *Set) Translator(locale string) i18n.Translator {
+	if s == nil {
+		return i18n.Default.Translator(locale)
+	}
+	return s.catalogs.Translator(locale)
+}
+
+// UseStyles sets the rules that pick the theme, emoji and thumbnail of each
+// card. cardstyle.Defaults is used until it is called.
+func (s *Set) UseStyles(styles cardstyle.Styles) {
+	if s != nil {
+		s.styles = styles
+	}
+}
+
+// Render executes the named templates and returns the message text and
+// card, with the slides listed in data.Slides.
+func (s *Set) Render(name string, data Data) (cliq.Message, error) {
+	if s == nil {
+		return cliq.Message{}, fmt.Errorf("templates are not loaded")
+	}
+	t, ok := s.templates[name]
+	if !ok {
+		return cliq.Message{}, fmt.Errorf("no template named %q", name)
+	}
+
+	// Bind "t" and the date functions to the locale and time zone of this
+	// message on a copy of the template
+	tr := s.Translator(data.Locale)
+	c := newClock(data.TimeZone, tr)
+	t, err := t.Clone()
+	if err != nil {
+		return cliq.Message{}, err
+	}
+	t.Funcs(template.FuncMap{"t": tr}).Funcs(c.funcs())
+
+	data.Style = s.styles.Resolve(issueFields(data))
+	text, err := execute(t, "text", data)
+	if err != nil {
+		return cliq.Message{}, err
+	}
+	card := &cliq.Card{Theme: data.Style.Theme, Thumbnail: data.Style.Thumbnail}
+	for part, field := range map[string]*string{"title": &card.Title, "theme": &card.Theme, "thumbnail": &card.Thumbnail} {
+		if t.Lookup(part) == nil {
+			continue
+		}
+		value, err := execute(t, part, data)
+		if err != nil {
+			return cliq.Message{}, err
+		}
+		if value != "" {
+			*field = value
+		}
+	}
+	return cliq.Message{Text: text, Card: card, Slides: s.slides(data, c)}, nil
+}
+
+// issueFields returns the fields of the issue in data.Raw, or nil.
+func issueFields(data Data) map[string]interface{} {
+	issue, _ := data.Raw["issue"].(map[string]interface{})
+	fields, _ := issue["fields"].(map[string]interface{})
+	return fields
+}
+
+// execute runs one named template and trims the surrounding whitespace that
+// template files tend to leave behind.
+func execute(t *template.Template, name string, data Data) (string, error) {
+	var b bytes.Buffer
+	if err := t.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
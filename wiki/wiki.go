@@ -0,0 +1,457 @@
+// Package wiki converts Jira wiki markup, the text format Jira Server and
+// Data Center use for descriptions and comments, to the markdown dialect of
+// Zoho Cliq messages.
+//
+// The testdata directory holds a corpus of markup samples, each *.wiki file
+// next to the *.md file it converts to.
+package wiki
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options customises the conversion.
+type Options struct {
+	// Mention renders a mention of a Jira user. accountID is set for
+	// [~accountid:...] mentions and username for [~name] ones. When nil,
+	// mentions are rendered as "@name".
+	Mention func(accountID string, username string) string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^h[1-6]\.\s+(.*)$`)
+	listPattern    = regexp.MustCompile(`^([*#]+|-)\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^-{4,}$`)
+)
+
+// emoticons are the Jira emoticons and the emoji they stand for.
+var emoticons = []struct{ markup, emoji string }{
+	{":)", "🙂"},
+	{":(", "🙁"},
+	{":P", "😛"},
+	{":D", "😀"},
+	{";)", "😉"},
+	{"(y)", "👍"},
+	{"(n)", "👎"},
+	{"(i)", "ℹ️"},
+	{"(/)", "✅"},
+	{"(x)", "❌"},
+	{"(!)", "⚠️"},
+	{"(?)", "❓"},
+	{"(+)", "➕"},
+	{"(-)", "➖"},
+	{"(on)", "💡"},
+	{"(off)", "💡"},
+	{"(*)", "⭐"},
+}
+
+// ToMarkdown converts markup to Cliq markdown. Macros it does not know are
+// dropped and their content kept.
+func ToMarkdown(markup string, opts Options) string {
+	c := converter{opts: opts}
+	markup = strings.ReplaceAll(markup, "\r\n", "\n")
+	return strings.TrimSpace(c.blocks(strings.Split(markup, "\n")))
+}
+
+type converter struct {
+	opts Options
+}
+
+// blocks converts lines of block markup. Blank lines between paragraphs are
+// dropped.
+func (c converter) blocks(lines []string) string {
+	var out []string
+	var list listState
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if m := listPattern.FindStringSubmatch(line); m != nil {
+			out = append(out, c.listItem(m[1], m[2], &list))
+			continue
+		}
+		list = listState{}
+
+		switch {
+		case line == "":
+		case macroName(line) == "code" || macroName(line) == "noformat":
+			body, next := macroBody(lines, i, macroName(line))
+			out = append(out, "```\n"+strings.Trim(body, "\n")+"\n```")
+			i = next
+		case macroName(line) == "quote":
+			body, next := macroBody(lines, i, "quote")
+			if s := c.blocks(strings.Split(body, "\n")); s != "" {
+				out = append(out, prefixLines(s, "> "))
+			}
+			i = next
+		case macroName(line) == "panel":
+			title := macroParam(line, "title")
+			body, next := macroBody(lines, i, "panel")
+			s := c.blocks(strings.Split(body, "\n"))
+			if title != "" {
+				s = strings.TrimSpace("*" + c.inline(title) + "*\n" + s)
+			}
+			if s != "" {
+				out = append(out, s)
+			}
+			i = next
+		case headingPattern.MatchString(line):
+			text := strings.TrimSpace(c.inline(headingPattern.FindStringSubmatch(line)[1]))
+			if text != "" {
+				out = append(out, "*"+text+"*")
+			}
+		case strings.HasPrefix(line, "bq. "):
+			out = append(out, prefixLines(c.inline(strings.TrimSpace(line[4:])), "> "))
+		case rulePattern.MatchString(line):
+			out = append(out, "---")
+		case strings.HasPrefix(line, "|"):
+			out = append(out, c.tableRow(line))
+		default:
+			if s := c.inline(line); strings.TrimSpace(s) != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// listState numbers the items of ordered lists at each nesting depth.
+type listState struct {
+	markers  string
+	counters []int
+}
+
+// listItem converts one item of a list. markers is the run of * and #
+// before the item; its length is the nesting depth.
+func (c converter) listItem(markers string, text string, list *listState) string {
+	depth := len(markers)
+	for len(list.counters) < depth {
+		list.counters = append(list.counters, 0)
+	}
+	list.counters = list.counters[:depth]
+	// A list of another kind at the same depth starts a new list
+	if len(list.markers) >= depth && list.markers[depth-1] != markers[depth-1] {
+		list.counters[depth-1] = 0
+	}
+	list.counters[depth-1]++
+	list.markers = markers
+
+	marker := "- "
+	if markers[depth-1] == '#' {
+		marker = strconv.Itoa(list.counters[depth-1]) + ". "
+	}
+	return strings.Repeat("  ", depth-1) + marker + c.inline(text)
+}
+
+// tableRow converts a table row. Header cells, written between ||, are
+// bold.
+func (c converter) tableRow(line string) string {
+	var cells []string
+	for _, cell := range splitCells(line) {
+		text := strings.TrimSpace(c.inline(cell.text))
+		if cell.header && text != "" {
+			text = "*" + text + "*"
+		}
+		cells = append(cells, text)
+	}
+	return strings.Join(cells, " | ")
+}
+
+type cell struct {
+	text   string
+	header bool
+}
+
+// splitCells splits a table row on | and ||, leaving the | inside links and
+// monospaced text alone.
+func splitCells(line string) []cell {
+	var cells []cell
+	var b strings.Builder
+	header := false
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '[' || strings.HasPrefix(line[i:], "{{"):
+			depth++
+		case (line[i] == ']' || strings.HasPrefix(line[i:], "}}")) && depth > 0:
+			depth--
+		case line[i] == '|' && depth == 0:
+			if i > 0 {
+				cells = append(cells, cell{text: b.String(), header: header})
+			}
+			b.Reset()
+			header = strings.HasPrefix(line[i:], "||")
+			if header {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(line[i])
+	}
+	if strings.TrimSpace(b.String()) != "" {
+		cells = append(cells, cell{text: b.String(), header: header})
+	}
+	return cells
+}
+
+// inline converts the inline markup of one line.
+func (c converter) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, `\\`):
+			b.WriteString("\n")
+			i += 2
+			continue
+		case rest[0] == '\\' && len(rest) > 1:
+			b.WriteByte(rest[1])
+			i += 2
+			continue
+		case strings.HasPrefix(rest, "{{"):
+			if end := strings.Index(rest[2:], "}}"); end > 0 {
+				b.WriteString("`" + rest[2:2+end] + "`")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '{':
+			// An unclosed macro is literal text
+			if name := macroName(rest); name == "color" || name == "anchor" {
+				if end := strings.IndexByte(rest, '}'); end > 0 {
+					i += end + 1
+					continue
+				}
+			}
+		case rest[0] == '[':
+			if end := strings.IndexByte(rest, ']'); end > 1 {
+				b.WriteString(c.link(rest[1:end]))
+				i += end + 1
+				continue
+			}
+		case rest[0] == '!':
+			if name, n := image(rest); n > 0 {
+				b.WriteString("[" + name + "]")
+				i += n
+				continue
+			}
+		case strings.HasPrefix(rest, "??"):
+			if inner, n := enclosed(s, i, "??"); n > 0 {
+				b.WriteString("_" + c.inline(inner) + "_")
+				i += n
+				continue
+			}
+		case strings.HasPrefix(rest, " --- "):
+			b.WriteString(" — ")
+			i += 5
+			continue
+		case strings.HasPrefix(rest, " -- "):
+			b.WriteString(" – ")
+			i += 4
+			continue
+		case strings.ContainsRune("*_-+^~", rune(rest[0])):
+			if inner, n := enclosed(s, i, rest[:1]); n > 0 {
+				b.WriteString(wrap(rest[0], c.inline(inner)))
+				i += n
+				continue
+			}
+		}
+		if emoji, n := emoticon(s, i); n > 0 {
+			b.WriteString(emoji)
+			i += n
+			continue
+		}
+		b.WriteByte(rest[0])
+		i++
+	}
+	return b.String()
+}
+
+// wrap returns text in the Cliq markup for the Jira marker. Underline,
+// superscript and subscript have no Cliq equivalent and are dropped.
+func wrap(marker byte, text string) string {
+	switch marker {
+	case '*':
+		return "*" + text + "*"
+	case '_':
+		return "_" + text + "_"
+	case '-':
+		return "~" + text + "~"
+	}
+	return text
+}
+
+// link converts the content of a [...] link.
+func (c converter) link(content string) string {
+	switch {
+	case strings.HasPrefix(content, "~"):
+		return c.mention(content[1:])
+	case strings.HasPrefix(content, "^"):
+		return "[" + content[1:] + "]"
+	case strings.HasPrefix(content, "#"):
+		return content[1:]
+	}
+	if text, target, ok := strings.Cut(content, "|"); ok {
+		// A second | separates a tooltip
+		target, _, _ = strings.Cut(target, "|")
+		target = strings.TrimSpace(target)
+		if strings.HasPrefix(target, "~") {
+			return c.mention(target[1:])
+		}
+		if strings.HasPrefix(target, "^") || strings.HasPrefix(target, "#") {
+			return c.inline(text)
+		}
+		return "[" + c.inline(text) + "](" + target + ")"
+	}
+	if strings.Contains(content, "://") || strings.HasPrefix(content, "mailto:") {
+		return strings.TrimPrefix(content, "mailto:")
+	}
+	return "[" + c.inline(content) + "]"
+}
+
+func (c converter) mention(user string) string {
+	var accountID, username string
+	if id, ok := strings.CutPrefix(user, "accountid:"); ok {
+		accountID = id
+	} else {
+		username = user
+	}
+	if c.opts.Mention != nil {
+		return c.opts.Mention(accountID, username)
+	}
+	return "@" + accountID + username
+}
+
+// enclosed finds markup that starts with marker at s[i] and ends with the
+// same marker on the line. Like Jira, it requires the opening marker to
+// start a word and the closing one to end a word, except for superscript
+// and subscript, as in x^2^ and H~2~O. It returns the text between the
+// markers and the length of the whole markup, or 0 when there is none.
+func enclosed(s string, i int, marker string) (string, int) {
+	inWord := marker == "^" || marker == "~"
+	if i > 0 && isWordChar(s[i-1]) && !inWord {
+		return "", 0
+	}
+	start := i + len(marker)
+	if start >= len(s) || s[start] == ' ' || s[start] == '\t' {
+		return "", 0
+	}
+	for j := start + 1; j+len(marker) <= len(s); j++ {
+		if !strings.HasPrefix(s[j:], marker) || s[j-1] == ' ' || s[j-1] == '\\' {
+			continue
+		}
+		end := j + len(marker)
+		if end < len(s) && isWordChar(s[end]) && !inWord {
+			continue
+		}
+		return s[start:j], end - i
+	}
+	return "", 0
+}
+
+// image finds an embedded image or attachment, such as !screenshot.png! or
+// !diagram.png|thumbnail!, at the start of s and returns its name and
+// length.
+func image(s string) (string, int) {
+	end := strings.IndexByte(s[1:], '!')
+	if end <= 0 {
+		return "", 0
+	}
+	content := s[1 : end+1]
+	if strings.TrimSpace(content) != content || !strings.Contains(content, ".") {
+		return "", 0
+	}
+	name, _, _ := strings.Cut(content, "|")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name, end + 2
+}
+
+// emoticon returns the emoji for an emoticon that starts a word at s[i].
+func emoticon(s string, i int) (string, int) {
+	if i > 0 && s[i-1] != ' ' {
+		return "", 0
+	}
+	for _, e := range emoticons {
+		if !strings.HasPrefix(s[i:], e.markup) {
+			continue
+		}
+		end := i + len(e.markup)
+		if end < len(s) && isWordChar(s[end]) {
+			continue
+		}
+		return e.emoji, len(e.markup)
+	}
+	return "", 0
+}
+
+// macroName returns the name of the macro, such as code in {code:java},
+// that starts line.
+func macroName(line string) string {
+	if !strings.HasPrefix(line, "{") {
+		return ""
+	}
+	end := strings.IndexAny(line, ":}")
+	if end < 0 {
+		return ""
+	}
+	return line[1:end]
+}
+
+// macroParam returns a parameter of the macro that starts line, such as the
+// title of {panel:title=Notes|borderStyle=dashed}.
+func macroParam(line string, key string) string {
+	end := strings.IndexByte(line, '}')
+	if end < 0 {
+		return ""
+	}
+	_, params, ok := strings.Cut(line[:end], ":")
+	if !ok {
+		return ""
+	}
+	for _, p := range strings.Split(params, "|") {
+		if k, v, ok := strings.Cut(p, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// macroBody returns the content of the macro that opens on lines[start],
+// up to its closing {name}, and the index of the line it closes on. The
+// content runs to the end of lines when the macro is not closed.
+func macroBody(lines []string, start int, name string) (string, int) {
+	closing := "{" + name + "}"
+	first := strings.TrimSpace(lines[start])
+	first = first[strings.IndexByte(first, '}')+1:]
+	if end := strings.Index(first, closing); end >= 0 {
+		return first[:end], start
+	}
+	body := []string{first}
+	for i := start + 1; i < len(lines); i++ {
+		if end := strings.Index(lines[i], closing); end >= 0 {
+			return strings.Join(append(body, lines[i][:end]), "\n"), i
+		}
+		body = append(body, lines[i])
+	}
+	return strings.Join(body, "\n"), len(lines)
+}
+
+func isWordChar(b byte) bool {
+	if b >= utf8.RuneSelf {
+		return true
+	}
+	r := rune(b)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func prefixLines(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,62 @@
+package wiki
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.wiki"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no *.wiki files in testdata")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".wiki")
+		t.Run(name, func(t *testing.T) {
+			markup, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(strings.TrimSuffix(file, ".wiki") + ".md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ToMarkdown(string(markup), Options{})
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("ToMarkdown(%s) =\n%s\nwant\n%s", file, got, want)
+			}
+		})
+	}
+}
+
+func TestUnclosedMacros(t *testing.T) {
+	tests := []struct {
+		markup, want string
+	}{
+		{"set {color:red this", "set {color:red this"},
+		{"see {anchor:top", "see {anchor:top"},
+		{"{color:red", "{color:red"},
+		{"{color", "{color"},
+		{"{", "{"},
+		{"{{code", "{{code"},
+		{"{color:red}red{color} and {color:blue", "red and {color:blue"},
+	}
+	for _, tt := range tests {
+		done := make(chan string, 1)
+		go func() { done <- ToMarkdown(tt.markup, Options{}) }()
+		select {
+		case got := <-done:
+			if got != tt.want {
+				t.Errorf("ToMarkdown(%q) = %q, want %q", tt.markup, got, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("ToMarkdown(%q) did not return", tt.markup)
+		}
+	}
+}